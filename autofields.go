@@ -0,0 +1,100 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+// Metadata keys of the automatic fields.
+const (
+	HostnameKey   = "hostname"
+	PIDKey        = "pid"
+	VersionKey    = "version"
+	InstanceIDKey = "instance_id"
+	SequenceKey   = "seq"
+)
+
+// AutoFields selects process information that is added to the metadata of every entry.
+// The fields are added after hooks run and take precedence over logger and Sublogger metadata with the same keys.
+type AutoFields struct {
+	Hostname bool `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	PID      bool `json:"pid,omitempty" yaml:"pid,omitempty"`
+	// Version is the main module version from the build info of the binary.
+	Version bool `json:"version,omitempty" yaml:"version,omitempty"`
+	// InstanceID is a random ID generated once per BasicLogger, which can be used to tell apart
+	// processes on the same host or restarts of the same process.
+	InstanceID bool `json:"instance_id,omitempty" yaml:"instance_id,omitempty"`
+	// Sequence is a number that is incremented for each entry written by the BasicLogger.
+	// Entries dropped by hooks or filtered out by level don't use up a number.
+	Sequence bool `json:"sequence,omitempty" yaml:"sequence,omitempty"`
+}
+
+func (af AutoFields) any() bool {
+	return af.Hostname || af.PID || af.Version || af.InstanceID || af.Sequence
+}
+
+var (
+	processInfoOnce sync.Once
+	hostname        string
+	buildVersion    string
+)
+
+func loadProcessInfo() {
+	hostname, _ = os.Hostname()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		buildVersion = info.Main.Version
+	}
+}
+
+func generateInstanceID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// InstanceID returns the random ID of this logger used for the instance_id automatic field.
+func (log *BasicLogger) InstanceID() string {
+	log.instanceIDOnce.Do(func() {
+		log.instanceID = generateInstanceID()
+	})
+	return log.instanceID
+}
+
+// SetAutoFields changes which automatic fields are added to entries.
+func (log *BasicLogger) SetAutoFields(fields AutoFields) {
+	log.configLock.Lock()
+	log.AutoFields = fields
+	log.configLock.Unlock()
+}
+
+// addAutoFields adds the automatic fields to the metadata, which must be owned by the caller.
+func (log *BasicLogger) addAutoFields(metadata map[string]interface{}, fields AutoFields) {
+	if fields.Hostname || fields.Version {
+		processInfoOnce.Do(loadProcessInfo)
+	}
+	if fields.Hostname {
+		metadata[HostnameKey] = hostname
+	}
+	if fields.PID {
+		metadata[PIDKey] = os.Getpid()
+	}
+	if fields.Version {
+		metadata[VersionKey] = buildVersion
+	}
+	if fields.InstanceID {
+		metadata[InstanceIDKey] = log.InstanceID()
+	}
+	if fields.Sequence {
+		metadata[SequenceKey] = log.sequence.Add(1)
+	}
+}
@@ -0,0 +1,97 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock returns the current time. It can be replaced to get deterministic timestamps in tests.
+type Clock func() time.Time
+
+// SetClock replaces the clock used for entry timestamps and log file names. Nil restores time.Now.
+func (log *BasicLogger) SetClock(clock Clock) {
+	log.configLock.Lock()
+	log.Clock = clock
+	log.configLock.Unlock()
+}
+
+// SetLocation changes the timezone that timestamps and log file names are rendered in. Nil means local time.
+func (log *BasicLogger) SetLocation(location *time.Location) {
+	log.configLock.Lock()
+	log.Location = location
+	log.configLock.Unlock()
+}
+
+// currentTime returns the current time from the clock in the given location.
+func currentTime(clock Clock, location *time.Location) time.Time {
+	if clock == nil {
+		return inLocation(time.Now(), location)
+	}
+	return inLocation(clock(), location)
+}
+
+// now returns the current time from the logger's clock in the configured location.
+func (log *BasicLogger) now() time.Time {
+	log.configLock.RLock()
+	clock, location := log.Clock, log.Location
+	log.configLock.RUnlock()
+	return currentTime(clock, location)
+}
+
+// getClock returns the logger's clock, or time.Now if none is set. Unlike now, the times it returns
+// keep their monotonic clock reading, so they can be used for measuring durations.
+func (log *BasicLogger) getClock() Clock {
+	log.configLock.RLock()
+	defer log.configLock.RUnlock()
+	if log.Clock == nil {
+		return time.Now
+	}
+	return log.Clock
+}
+
+// inLocation converts the time to the given location, or local time if the location is nil.
+func inLocation(ts time.Time, location *time.Location) time.Time {
+	if location == nil {
+		return ts.Local()
+	}
+	return ts.In(location)
+}
+
+// loadLocation parses a timezone name from a config. An empty name means local time.
+func loadLocation(name string) (*time.Location, error) {
+	if len(name) == 0 {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return location, nil
+}
+
+// RawAt logs the message with the given timestamp instead of the current time, e.g. when replaying entries from another source.
+// If ts is zero, the current time is used like in Raw.
+func (log *BasicLogger) RawAt(ts time.Time, level Level, extraMetadata map[string]interface{}, module, message string) {
+	log.raw(ts, level, extraMetadata, module, message, false)
+}
+
+// LogAt formats the given parts with fmt.Sprint and logs the result with the given level and timestamp.
+func (log *BasicLogger) LogAt(ts time.Time, level Level, parts ...interface{}) {
+	if log.enabled(level, "") {
+		log.raw(ts, level, nil, "", fmt.Sprint(ResolveLazy(parts)...), false)
+	}
+}
+
+// LogAt formats the given parts with fmt.Sprint and logs the result with the given level and timestamp.
+func (log *Sublogger) LogAt(ts time.Time, level Level, parts ...interface{}) {
+	if log.enabled(level) {
+		log.write(ts, level, fmt.Sprint(ResolveLazy(parts)...))
+	}
+}
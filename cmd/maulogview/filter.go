@@ -0,0 +1,137 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"maunium.net/go/maulogger/v2"
+	"maunium.net/go/maulogger/v2/maulogread"
+)
+
+// Filter decides which entries are shown.
+type Filter struct {
+	MinLevel     maulogger.Level
+	ModulePrefix string
+	Since        time.Time
+	Until        time.Time
+	Metadata     map[string]string
+}
+
+// Match checks if the entry passes all the filters.
+func (f *Filter) Match(entry *maulogread.Entry) bool {
+	if entry.Level.Severity < f.MinLevel.Severity {
+		return false
+	} else if len(f.ModulePrefix) > 0 && entry.Module != f.ModulePrefix && !strings.HasPrefix(entry.Module, f.ModulePrefix+"/") {
+		return false
+	} else if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	} else if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	for key, expected := range f.Metadata {
+		value, ok := entry.Metadata[key]
+		if !ok || fmt.Sprint(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// Printer writes entries in the same layout BasicLogger uses for console output.
+type Printer struct {
+	Output     io.Writer
+	TimeFormat string
+	Color      bool
+}
+
+// Print writes a single entry.
+func (p *Printer) Print(entry *maulogread.Entry) error {
+	var buf strings.Builder
+	if p.Color {
+		buf.WriteString(entry.Level.GetColor())
+	}
+	buf.WriteByte('[')
+	buf.WriteString(entry.Time.Local().Format(p.TimeFormat))
+	buf.WriteString("] [")
+	if len(entry.Module) > 0 {
+		buf.WriteString(entry.Module)
+		buf.WriteByte('/')
+	}
+	buf.WriteString(entry.Level.Name)
+	buf.WriteString("] ")
+	buf.WriteString(entry.Message)
+	if len(entry.Metadata) > 0 {
+		buf.WriteByte(' ')
+		buf.Write(maulogger.AppendMetadataText(nil, entry.Metadata))
+	}
+	if len(entry.Caller) > 0 {
+		buf.WriteString(" caller=")
+		buf.WriteString(entry.Caller)
+	}
+	if p.Color {
+		buf.WriteString(entry.Level.GetReset())
+	}
+	buf.WriteByte('\n')
+	_, err := io.WriteString(p.Output, buf.String())
+	return err
+}
+
+type mergeSource struct {
+	reader *maulogread.Reader
+	next   *maulogread.Entry
+}
+
+// Merge reads all the given readers and passes the entries to the handler in chronological order.
+func Merge(readers []*maulogread.Reader, handler func(*maulogread.Entry) error) error {
+	sources := make([]*mergeSource, 0, len(readers))
+	for _, reader := range readers {
+		source := &mergeSource{reader: reader}
+		if err := source.advance(); err != nil {
+			return err
+		} else if source.next != nil {
+			sources = append(sources, source)
+		}
+	}
+	for len(sources) > 0 {
+		earliest := 0
+		for i, source := range sources {
+			if source.next.Time.Before(sources[earliest].next.Time) {
+				earliest = i
+			}
+		}
+		source := sources[earliest]
+		if err := handler(source.next); err != nil {
+			return err
+		} else if err = source.advance(); err != nil {
+			return err
+		} else if source.next == nil {
+			sources = append(sources[:earliest], sources[earliest+1:]...)
+		}
+	}
+	return nil
+}
+
+func (source *mergeSource) advance() error {
+	for {
+		entry, err := source.reader.Next()
+		if errors.Is(err, io.EOF) {
+			source.next = nil
+			return nil
+		} else if errors.Is(err, maulogread.ErrNotLogLine) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		source.next = entry
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"maunium.net/go/maulogger/v2"
+	"maunium.net/go/maulogger/v2/maulogread"
+)
+
+func TestFilterMatch(t *testing.T) {
+	base := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	entry := &maulogread.Entry{
+		Time:     base,
+		Level:    maulogger.LevelWarn,
+		Module:   "bridge/sync",
+		Message:  "meow",
+		Metadata: map[string]interface{}{"room_id": "!meow", "count": float64(5)},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		match  bool
+	}{
+		{"Empty", Filter{}, true},
+		{"LevelEqual", Filter{MinLevel: maulogger.LevelWarn}, true},
+		{"LevelAbove", Filter{MinLevel: maulogger.LevelError}, false},
+		{"ModuleExact", Filter{ModulePrefix: "bridge/sync"}, true},
+		{"ModuleParent", Filter{ModulePrefix: "bridge"}, true},
+		{"ModuleNamePrefix", Filter{ModulePrefix: "bri"}, false},
+		{"ModuleOther", Filter{ModulePrefix: "crypto"}, false},
+		{"Since", Filter{Since: base.Add(-time.Second)}, true},
+		{"SinceAfter", Filter{Since: base.Add(time.Second)}, false},
+		{"Until", Filter{Until: base.Add(time.Second)}, true},
+		{"UntilBefore", Filter{Until: base.Add(-time.Second)}, false},
+		{"Metadata", Filter{Metadata: map[string]string{"room_id": "!meow", "count": "5"}}, true},
+		{"MetadataMismatch", Filter{Metadata: map[string]string{"room_id": "!woof"}}, false},
+		{"MetadataMissing", Filter{Metadata: map[string]string{"user_id": "@meow"}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if match := test.filter.Match(entry); match != test.match {
+				t.Errorf("Expected Match to return %t, got %t", test.match, match)
+			}
+		})
+	}
+}
+
+func TestMergeChronological(t *testing.T) {
+	first := maulogread.NewReader(strings.NewReader(strings.Join([]string{
+		`not a log line`,
+		`{"time":"2023-01-02T15:04:01Z","level":"INFO","message":"a1"}`,
+		`{"time":"2023-01-02T15:04:03Z","level":"INFO","message":"a3"}`,
+		`{"time":"2023-01-02T15:04:06Z","level":"INFO","message":"a6"}`,
+	}, "\n")))
+	second := maulogread.NewReader(strings.NewReader(strings.Join([]string{
+		`{"time":"2023-01-02T15:04:02Z","level":"INFO","message":"b2"}`,
+		`{"time":"2023-01-02T15:04:04Z","level":"INFO","message":"b4"}`,
+		`{"time":"2023-01-02T15:04:05Z","level":"INFO","message":"b5"}`,
+	}, "\n")))
+	empty := maulogread.NewReader(strings.NewReader(""))
+
+	var messages []string
+	err := Merge([]*maulogread.Reader{first, empty, second}, func(entry *maulogread.Entry) error {
+		messages = append(messages, entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined := strings.Join(messages, " "); joined != "a1 b2 a3 b4 b5 a6" {
+		t.Errorf("Entries weren't merged chronologically: %s", joined)
+	}
+}
+
+func TestPrinterPrint(t *testing.T) {
+	entry := &maulogread.Entry{
+		Time:     time.Date(2023, 1, 2, 15, 4, 5, 0, time.Local),
+		Level:    maulogger.LevelWarn,
+		Module:   "bridge",
+		Message:  "meow",
+		Metadata: map[string]interface{}{"room_id": "!meow"},
+		Caller:   "bridge/sync.go:12",
+	}
+	tests := []struct {
+		name     string
+		color    bool
+		expected string
+	}{
+		{"Plain", false, "[2023-01-02 15:04:05] [bridge/WARN] meow room_id=!meow caller=bridge/sync.go:12\n"},
+		{"Color", true, maulogger.LevelWarn.GetColor() + "[2023-01-02 15:04:05] [bridge/WARN] meow room_id=!meow caller=bridge/sync.go:12" + maulogger.LevelWarn.GetReset() + "\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			printer := &Printer{Output: &out, TimeFormat: "2006-01-02 15:04:05", Color: test.color}
+			if err := printer.Print(entry); err != nil {
+				t.Fatal(err)
+			} else if out.String() != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+}
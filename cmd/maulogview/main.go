@@ -0,0 +1,167 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// maulogview is a viewer for log files written by maulogger.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"maunium.net/go/maulogger/v2"
+	"maunium.net/go/maulogger/v2/maulogread"
+)
+
+type multiFlag []string
+
+func (mf *multiFlag) String() string {
+	return strings.Join(*mf, ", ")
+}
+
+func (mf *multiFlag) Set(value string) error {
+	*mf = append(*mf, value)
+	return nil
+}
+
+var (
+	minLevel       = flag.String("level", "DEBUG", "Minimum level to show")
+	modulePrefix   = flag.String("module", "", "Only show entries from this module and its submodules")
+	since          = flag.String("since", "", "Only show entries after this time (RFC3339 or a duration like 1h30m)")
+	until          = flag.String("until", "", "Only show entries before this time (RFC3339 or a duration like 1h30m)")
+	follow         = flag.Bool("f", false, "Keep reading new entries from the last file")
+	format         = flag.String("format", "auto", "Input format: auto, text or json")
+	timeFormat     = flag.String("time-format", maulogger.DefaultLogger.TimeFormat, "TimeFormat used when writing text logs, also used for output")
+	rotatedDir     = flag.String("rotated", "", "Read all rotated log files from this directory")
+	fileFormat     = flag.String("file-format", "%[1]s-%02[2]d.log", "Format of rotated file names with the date and index as arguments, like file.path_format in the config")
+	fileTimeFormat = flag.String("file-time-format", maulogger.DefaultLogger.FileTimeFormat, "FileTimeFormat used in rotated file names")
+	noColor        = flag.Bool("no-color", false, "Disable colored output")
+	pollInterval   = flag.Duration("poll-interval", 500*time.Millisecond, "How often to check for new data in follow mode")
+	metadata       multiFlag
+)
+
+func init() {
+	flag.Var(&metadata, "meta", "Only show entries with the given metadata key=value (can be repeated)")
+	flag.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: %s [flags] [files...]\n\nFlags:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func parseTimeFlag(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	} else if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func main() {
+	flag.Parse()
+	err := run()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var filter Filter
+	var err error
+	if filter.MinLevel, err = maulogger.ParseLevel(*minLevel); err != nil {
+		return fmt.Errorf("invalid -level: %w", err)
+	}
+	filter.ModulePrefix = *modulePrefix
+	if filter.Since, err = parseTimeFlag(*since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	} else if filter.Until, err = parseTimeFlag(*until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+	filter.Metadata = make(map[string]string, len(metadata))
+	for _, pair := range metadata {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid -meta %q: expected key=value", pair)
+		}
+		filter.Metadata[parts[0]] = parts[1]
+	}
+
+	printer := &Printer{Output: os.Stdout, TimeFormat: *timeFormat, Color: !*noColor}
+	handler := func(entry *maulogread.Entry) error {
+		if filter.Match(entry) {
+			return printer.Print(entry)
+		}
+		return nil
+	}
+
+	paths := flag.Args()
+	if len(*rotatedDir) > 0 {
+		dir, nameFormat := *rotatedDir, *fileFormat
+		rotated, err := maulogread.RotatedFiles(*fileTimeFormat, func(now string, i int) string {
+			return filepath.Join(dir, fmt.Sprintf(nameFormat, now, i))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find rotated files: %w", err)
+		}
+		paths = append(rotated, paths...)
+	}
+
+	readers := make([]*maulogread.Reader, 0, len(paths))
+	if len(paths) == 0 {
+		if *follow {
+			return errors.New("follow mode requires a file")
+		}
+		readers = append(readers, newReader(os.Stdin))
+	}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, newReader(file))
+	}
+	if *follow {
+		// The last file is followed separately below.
+		readers = readers[:len(readers)-1]
+	}
+	if err = Merge(readers, handler); err != nil {
+		return err
+	}
+
+	if *follow {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		return maulogread.Follow(ctx, paths[len(paths)-1], newParser(), *pollInterval, handler)
+	}
+	return nil
+}
+
+func newParser() *maulogread.Parser {
+	parser := maulogread.NewParser()
+	parser.TimeFormat = *timeFormat
+	switch strings.ToLower(*format) {
+	case "text":
+		parser.Format = maulogread.FormatText
+	case "json":
+		parser.Format = maulogread.FormatJSON
+	}
+	return parser
+}
+
+func newReader(input io.Reader) *maulogread.Reader {
+	reader := maulogread.NewReader(input)
+	reader.Parser = newParser()
+	return reader
+}
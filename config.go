@@ -0,0 +1,362 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration that is encoded as a string like "1m30s" in config files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ConsoleConfig configures the stdout/stderr output.
+type ConsoleConfig struct {
+	// Level is the minimum level printed to the console. Corresponds to BasicLogger.PrintLevel.
+	// Empty means INFO.
+	Level    string `json:"level,omitempty" yaml:"level,omitempty"`
+	JSON     bool   `json:"json,omitempty" yaml:"json,omitempty"`
+	Disabled bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	// MetadataLine renders metadata on a separate line in text mode. Corresponds to BasicLogger.StdoutMetadataLine.
+	MetadataLine bool `json:"metadata_line,omitempty" yaml:"metadata_line,omitempty"`
+	// Limits restricts the size of entries printed to the console. Corresponds to BasicLogger.StdoutLimits.
+	Limits SizeLimits `json:"limits" yaml:"limits"`
+}
+
+// level parses Level, defaulting to LevelInfo if it's empty.
+func (cc *ConsoleConfig) level() (Level, error) {
+	if len(strings.TrimSpace(cc.Level)) == 0 {
+		return LevelInfo, nil
+	}
+	return ParseLevel(cc.Level)
+}
+
+// FileConfig configures the log file output.
+type FileConfig struct {
+	// PathFormat is the file path as a fmt format string. The first argument is the date formatted
+	// with TimeFormat and the second one is the index of the file for that date, e.g. "logs/%[1]s-%02[2]d.log".
+	// If empty, no log file is opened.
+	PathFormat string `json:"path_format,omitempty" yaml:"path_format,omitempty"`
+	// TimeFormat is the date format used in file names. Corresponds to BasicLogger.FileTimeFormat.
+	TimeFormat string `json:"time_format,omitempty" yaml:"time_format,omitempty"`
+	// Mode is the file permissions as an octal string. Defaults to "0600".
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	JSON bool   `json:"json,omitempty" yaml:"json,omitempty"`
+	// Limits restricts the size of entries written to the file. Corresponds to BasicLogger.FileLimits.
+	Limits SizeLimits `json:"limits" yaml:"limits"`
+}
+
+// SinkConfig configures a network or HTTP sink.
+type SinkConfig struct {
+	// Type is "tcp", "udp" or "http".
+	Type string `json:"type" yaml:"type"`
+
+	// Address and Spool are used by the tcp and udp types.
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	Spool   string `json:"spool,omitempty" yaml:"spool,omitempty"`
+
+	// The rest of the fields are used by the http type.
+	URL           string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Format        string            `json:"format,omitempty" yaml:"format,omitempty"`
+	Gzip          bool              `json:"gzip,omitempty" yaml:"gzip,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	BatchSize     int               `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	FlushInterval Duration          `json:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+}
+
+// RedactConfig configures the Redactor used for all outputs.
+type RedactConfig struct {
+	Keys     []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+	Mask     string   `json:"mask,omitempty" yaml:"mask,omitempty"`
+}
+
+// Config is a declarative configuration for a BasicLogger.
+type Config struct {
+	TimeFormat string `json:"time_format,omitempty" yaml:"time_format,omitempty"`
+	// Timezone is the IANA name of the timezone used for timestamps and file names, e.g. "UTC" or
+	// "Europe/Helsinki". Empty means local time. Corresponds to BasicLogger.Location.
+	Timezone     string                 `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	ModuleLevels map[string]string      `json:"module_levels,omitempty" yaml:"module_levels,omitempty"`
+
+	Console ConsoleConfig `json:"console" yaml:"console"`
+	File    FileConfig    `json:"file" yaml:"file"`
+	Sinks   []SinkConfig  `json:"sinks,omitempty" yaml:"sinks,omitempty"`
+	Redact  *RedactConfig `json:"redact,omitempty" yaml:"redact,omitempty"`
+	// Text controls how newlines and control characters in messages are written in text mode.
+	Text TextPolicy `json:"text" yaml:"text"`
+	// AutoFields selects process information added to every entry. Corresponds to BasicLogger.AutoFields.
+	AutoFields AutoFields `json:"auto_fields" yaml:"auto_fields"`
+	// SinkLimits restricts the size of entries sent to sinks. Corresponds to BasicLogger.SinkLimits.
+	SinkLimits SizeLimits `json:"sink_limits" yaml:"sink_limits"`
+}
+
+// DefaultConfig returns a config with the same settings as Create.
+func DefaultConfig() *Config {
+	return &Config{
+		TimeFormat: "15:04:05 02.01.2006",
+		Console: ConsoleConfig{
+			Level: LevelInfo.Name,
+		},
+		File: FileConfig{
+			TimeFormat: "2006-01-02",
+			Mode:       "0600",
+		},
+	}
+}
+
+// LoadConfig reads a config file on top of DefaultConfig. Files ending with .json are parsed as JSON,
+// everything else as YAML. Environment variables are not applied automatically, use LoadEnv for that.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, cfg)
+	} else {
+		err = yaml.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Environment variables read by Config.LoadEnv.
+const (
+	EnvLevel          = "MAULOG_LEVEL"
+	EnvModuleLevels   = "MAULOG_MODULE_LEVELS"
+	EnvFile           = "MAULOG_FILE"
+	EnvFileTimeFormat = "MAULOG_FILE_TIME_FORMAT"
+	EnvFileMode       = "MAULOG_FILE_MODE"
+	EnvFileJSON       = "MAULOG_FILE_JSON"
+	EnvStdoutJSON     = "MAULOG_STDOUT_JSON"
+	EnvStdoutMetaLine = "MAULOG_STDOUT_METADATA_LINE"
+	EnvTimeFormat     = "MAULOG_TIME_FORMAT"
+	EnvTimezone       = "MAULOG_TIMEZONE"
+)
+
+// LoadEnv overrides config fields with the MAULOG_* environment variables that are set.
+//
+// MAULOG_MODULE_LEVELS is a comma-separated list of module=level pairs, e.g. "db=warn,bridge/sync=debug".
+func (cfg *Config) LoadEnv() error {
+	if val, ok := os.LookupEnv(EnvLevel); ok {
+		cfg.Console.Level = val
+	}
+	if val, ok := os.LookupEnv(EnvModuleLevels); ok {
+		if cfg.ModuleLevels == nil {
+			cfg.ModuleLevels = make(map[string]string)
+		}
+		for _, pair := range strings.Split(val, ",") {
+			if len(strings.TrimSpace(pair)) == 0 {
+				continue
+			}
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid %s: expected module=level, got %q", EnvModuleLevels, pair)
+			}
+			cfg.ModuleLevels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	if val, ok := os.LookupEnv(EnvFile); ok {
+		cfg.File.PathFormat = val
+	}
+	if val, ok := os.LookupEnv(EnvFileTimeFormat); ok {
+		cfg.File.TimeFormat = val
+	}
+	if val, ok := os.LookupEnv(EnvFileMode); ok {
+		cfg.File.Mode = val
+	}
+	if val, ok := os.LookupEnv(EnvTimeFormat); ok {
+		cfg.TimeFormat = val
+	}
+	if val, ok := os.LookupEnv(EnvTimezone); ok {
+		cfg.Timezone = val
+	}
+	var err error
+	if val, ok := os.LookupEnv(EnvFileJSON); ok {
+		if cfg.File.JSON, err = strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvFileJSON, err)
+		}
+	}
+	if val, ok := os.LookupEnv(EnvStdoutJSON); ok {
+		if cfg.Console.JSON, err = strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvStdoutJSON, err)
+		}
+	}
+	if val, ok := os.LookupEnv(EnvStdoutMetaLine); ok {
+		if cfg.Console.MetadataLine, err = strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvStdoutMetaLine, err)
+		}
+	}
+	return nil
+}
+
+func (sl *SizeLimits) validate(name string, cfgErr *ConfigError) {
+	if sl.MaxMessage < 0 || sl.MaxValue < 0 || sl.MaxEntry < 0 {
+		cfgErr.addf("%s must not be negative", name)
+	}
+}
+
+// ConfigError contains all problems found by Config.Validate.
+type ConfigError struct {
+	Problems []string
+}
+
+func (err *ConfigError) Error() string {
+	return "invalid logger config: " + strings.Join(err.Problems, "; ")
+}
+
+func (err *ConfigError) addf(format string, args ...interface{}) {
+	err.Problems = append(err.Problems, fmt.Sprintf(format, args...))
+}
+
+func parseFileMode(mode string) (os.FileMode, error) {
+	if len(mode) == 0 {
+		return 0600, nil
+	}
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, err
+	} else if parsed > 0777 {
+		return 0, fmt.Errorf("mode %s has bits outside 0777", mode)
+	}
+	return os.FileMode(parsed), nil
+}
+
+// Validate checks the config for errors. If there are any, the returned error is a *ConfigError
+// that lists all of them.
+func (cfg *Config) Validate() error {
+	var cfgErr ConfigError
+	if len(cfg.TimeFormat) == 0 {
+		cfgErr.addf("time_format must not be empty")
+	}
+	if _, err := loadLocation(cfg.Timezone); err != nil {
+		cfgErr.addf("timezone: %v", err)
+	}
+	if _, err := cfg.Console.level(); err != nil {
+		cfgErr.addf("console.level: %v", err)
+	}
+	for module, level := range cfg.ModuleLevels {
+		if _, err := ParseLevel(level); err != nil {
+			cfgErr.addf("module_levels.%s: %v", module, err)
+		}
+	}
+	if len(cfg.File.PathFormat) > 0 {
+		if !strings.Contains(cfg.File.PathFormat, "%") {
+			cfgErr.addf("file.path_format must contain a %% verb for the date")
+		} else if strings.Contains(fmt.Sprintf(cfg.File.PathFormat, "", 0), "%!") {
+			cfgErr.addf("file.path_format %q is not a valid format for a date string and an index", cfg.File.PathFormat)
+		} else if fmt.Sprintf(cfg.File.PathFormat, "2006-01-02", 1) == fmt.Sprintf(cfg.File.PathFormat, "2006-01-02", 2) {
+			cfgErr.addf("file.path_format %q must contain a verb for the file index", cfg.File.PathFormat)
+		}
+		if len(cfg.File.TimeFormat) == 0 {
+			cfgErr.addf("file.time_format must not be empty")
+		}
+		if _, err := parseFileMode(cfg.File.Mode); err != nil {
+			cfgErr.addf("file.mode: %v", err)
+		}
+	}
+	for i, sink := range cfg.Sinks {
+		switch sink.Type {
+		case "tcp", "udp":
+			if len(sink.Address) == 0 {
+				cfgErr.addf("sinks[%d].address is required for %s sinks", i, sink.Type)
+			}
+		case "http":
+			if parsed, err := url.Parse(sink.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+				cfgErr.addf("sinks[%d].url must be a http(s) URL", i)
+			}
+			if sink.Format != "" && sink.Format != "ndjson" && sink.Format != "array" {
+				cfgErr.addf("sinks[%d].format must be ndjson or array", i)
+			}
+			if sink.BatchSize < 0 {
+				cfgErr.addf("sinks[%d].batch_size must not be negative", i)
+			}
+			if sink.FlushInterval < 0 {
+				cfgErr.addf("sinks[%d].flush_interval must not be negative", i)
+			}
+		default:
+			cfgErr.addf("sinks[%d].type must be tcp, udp or http", i)
+		}
+	}
+	cfg.Console.Limits.validate("console.limits", &cfgErr)
+	cfg.File.Limits.validate("file.limits", &cfgErr)
+	cfg.SinkLimits.validate("sink_limits", &cfgErr)
+	if cfg.Redact != nil {
+		for i, pattern := range cfg.Redact.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				cfgErr.addf("redact.patterns[%d]: %v", i, err)
+			}
+		}
+	}
+	if len(cfgErr.Problems) > 0 {
+		return &cfgErr
+	}
+	return nil
+}
+
+func (sink *SinkConfig) build() (Sink, error) {
+	switch sink.Type {
+	case "tcp", "udp":
+		return NewNetSink(sink.Type, sink.Address, sink.Spool)
+	case "http":
+		httpSink := NewHTTPSink(sink.URL)
+		if sink.Format == "array" {
+			httpSink.Format = HTTPBatchJSONArray
+		}
+		httpSink.Gzip = sink.Gzip
+		for key, value := range sink.Headers {
+			httpSink.Header.Set(key, value)
+		}
+		if sink.BatchSize > 0 {
+			httpSink.MaxBatchSize = sink.BatchSize
+		}
+		if sink.FlushInterval > 0 {
+			httpSink.FlushInterval = time.Duration(sink.FlushInterval)
+		}
+		return httpSink, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sink.Type)
+	}
+}
+
+// Build validates the config and creates a logger from it. If a file path is configured,
+// the log file is opened and all sinks are started.
+func (cfg *Config) Build() (*BasicLogger, error) {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	if err := log.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
@@ -0,0 +1,156 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout runs fn with os.Stdout redirected and returns everything written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStdout := os.Stdout
+	os.Stdout = writer
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	defer func() {
+		os.Stdout = origStdout
+	}()
+	fn()
+	_ = writer.Close()
+	return <-output
+}
+
+func TestModuleLevelBelowPrintLevel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ModuleLevels = map[string]string{"bridge/sync": "debug", "db": "error"}
+	log, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sync := log.Sub("bridge").Sub("sync")
+	if !sync.Enabled(LevelDebug) {
+		t.Error("Expected debug to be enabled for bridge/sync")
+	} else if log.Sub("bridge").Enabled(LevelDebug) {
+		t.Error("Expected debug to be disabled for bridge")
+	} else if log.Sub("db").Enabled(LevelWarn) {
+		t.Error("Expected warn to be disabled for db")
+	}
+	output := captureStdout(t, func() {
+		sync.Debugln("Sync debug")
+		sync.Sub("inner").Debugln("Inner debug")
+		log.Sub("bridge").Debugln("Bridge debug")
+		log.Sub("db").Warnln("DB warning")
+		log.Sub("db").Errorln("DB error")
+	})
+	if !strings.Contains(output, "Sync debug") || !strings.Contains(output, "Inner debug") {
+		t.Errorf("Expected bridge/sync debug entries in output, got %q", output)
+	} else if strings.Contains(output, "Bridge debug") || strings.Contains(output, "DB warning") {
+		t.Errorf("Unexpected entries in output: %q", output)
+	}
+}
+
+func TestEmptyConsoleLevel(t *testing.T) {
+	log, err := (&Config{TimeFormat: "15:04"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	} else if log.PrintLevel != LevelInfo.Severity {
+		t.Errorf("Expected empty console level to mean INFO, got severity %d", log.PrintLevel)
+	}
+}
+
+func TestReloadKeepsManualWriter(t *testing.T) {
+	log := Createm(nil).(*BasicLogger)
+	file, err := os.CreateTemp(t.TempDir(), "manual-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.SetWriter(file)
+	if err = log.Reload(DefaultConfig()); err != nil {
+		t.Fatal(err)
+	} else if !log.hasWriter() {
+		t.Fatal("Reload without a file path closed the manually opened file")
+	}
+	log.Infoln("Still here")
+	if err = log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(file.Name())
+	if !strings.Contains(string(data), "Still here") {
+		t.Errorf("Expected entry in manually opened file, got %q", data)
+	}
+}
+
+func TestValidatePathFormatWithoutIndex(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.File.PathFormat = "logs/%[1]s.log"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "file index") {
+		t.Errorf("Expected path_format without an index verb to be rejected, got %v", err)
+	}
+	cfg.File.PathFormat = "logs/%[1]s-%02[2]d.log"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected path_format with an index verb to be valid, got %v", err)
+	}
+}
+
+func TestOpenLogFileWithoutIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meow.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := openLogFile(time.Now(), "2006-01-02", func(string, int) string { return path }, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if file.Name() != path {
+		t.Errorf("Expected the existing file %s to be opened, got %s", path, file.Name())
+	}
+}
+
+func TestConsoleDisabledWithModuleLevels(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Console.Disabled = true
+	cfg.ModuleLevels = map[string]string{"bridge": "debug"}
+	log, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := captureStdout(t, func() {
+		log.Sub("bridge").Debugln("Bridge debug")
+		log.Sub("bridge").Infoln("Bridge info")
+		log.Infoln("Root info")
+	})
+	if len(output) > 0 {
+		t.Errorf("Expected no console output when the console is disabled, got %q", output)
+	} else if log.Sub("bridge").Enabled(LevelDebug) {
+		t.Error("Expected debug to be disabled for bridge without any outputs")
+	}
+}
+
+func TestWatchConfigZeroInterval(t *testing.T) {
+	log := Createm(nil).(*BasicLogger)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := log.WatchConfig(ctx, filepath.Join(t.TempDir(), "config.yaml"), 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected WatchConfig to run until the context is done, got %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPooledBufferSize is the largest buffer that is returned to the pool.
+// Bigger buffers are left for the garbage collector so one huge line doesn't pin memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+// lineBuffer holds reusable buffers for encoding a single log line.
+type lineBuffer struct {
+	text    []byte
+	json    bytes.Buffer
+	encoder *json.Encoder
+}
+
+var lineBufferPool = sync.Pool{
+	New: func() interface{} {
+		lb := &lineBuffer{text: make([]byte, 0, 256)}
+		lb.encoder = json.NewEncoder(&lb.json)
+		return lb
+	},
+}
+
+func getLineBuffer() *lineBuffer {
+	return lineBufferPool.Get().(*lineBuffer)
+}
+
+func (lb *lineBuffer) release() {
+	if cap(lb.text) > maxPooledBufferSize || lb.json.Cap() > maxPooledBufferSize {
+		return
+	}
+	lb.text = lb.text[:0]
+	lb.json.Reset()
+	lineBufferPool.Put(lb)
+}
+
+// encodeJSON encodes the line as JSON with a trailing newline. The returned slice is only valid
+// until the next encodeJSON call or release.
+func (lb *lineBuffer) encodeJSON(line logLine) ([]byte, error) {
+	lb.json.Reset()
+	err := lb.encoder.Encode(&line)
+	return lb.json.Bytes(), err
+}
+
+// outputFormat is the encoding used for a single output.
+type outputFormat struct {
+	formatter    Formatter
+	json         bool
+	color        bool
+	metadataLine bool
+}
+
+// encode encodes the line including a trailing newline. The returned slice is only valid until
+// the next encode call or release.
+func (lb *lineBuffer) encode(line logLine, level Level, format outputFormat) ([]byte, error) {
+	if format.formatter != nil {
+		entry := line.entry(level)
+		var err error
+		lb.text, err = format.formatter.Format(lb.text[:0], &entry)
+		return lb.text, err
+	} else if format.json {
+		return lb.encodeJSON(line)
+	}
+	lb.text = line.appendTextLine(lb.text[:0], level, format.color, format.metadataLine)
+	return lb.text, nil
+}
+
+// appendText appends the text representation of the line to the buffer, including metadata and the caller.
+func (ll *logLine) appendText(buf []byte) []byte {
+	buf = ll.appendTextMessage(buf)
+	if len(ll.Metadata) > 0 || len(ll.Caller) > 0 {
+		buf = append(buf, ' ')
+		buf = ll.appendTextFields(buf)
+	}
+	return buf
+}
+
+// appendTextFields appends the metadata and caller as key=value pairs.
+func (ll *logLine) appendTextFields(buf []byte) []byte {
+	buf = AppendMetadataText(buf, ll.Metadata)
+	if len(ll.Caller) > 0 {
+		if len(ll.Metadata) > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "caller="...)
+		buf = appendTextString(buf, ll.Caller)
+	}
+	return buf
+}
+
+// appendTextLine appends the full text line including a trailing newline to the buffer.
+// If metadataLine is true, metadata is written on an indented second line.
+func (ll *logLine) appendTextLine(buf []byte, level Level, color, metadataLine bool) []byte {
+	if color {
+		buf = level.appendColor(buf)
+	}
+	if metadataLine && (len(ll.Metadata) > 0 || len(ll.Caller) > 0) {
+		buf = ll.appendTextMessage(buf)
+		if color {
+			buf = append(buf, level.GetReset()...)
+		}
+		buf = append(buf, "\n    "...)
+		buf = ll.appendTextFields(buf)
+	} else {
+		buf = ll.appendText(buf)
+		if color {
+			buf = append(buf, level.GetReset()...)
+		}
+	}
+	return append(buf, '\n')
+}
+
+// appendTextMessage appends the text representation of the line without metadata to the buffer.
+func (ll *logLine) appendTextMessage(buf []byte) []byte {
+	lineStart := len(buf)
+	buf = append(buf, '[')
+	buf = ll.Time.AppendFormat(buf, ll.timeFormat)
+	buf = append(buf, "] ["...)
+	if len(ll.Module) > 0 {
+		buf = append(buf, ll.Module...)
+		buf = append(buf, '/')
+	}
+	buf = append(buf, ll.Level...)
+	buf = append(buf, "] "...)
+	return ll.policy.appendMessage(buf, ll.Message, lineStart)
+}
+
+// appendColor appends the same escape code as GetColor to the buffer.
+func (lvl Level) appendColor(buf []byte) []byte {
+	if lvl.Color < 0 {
+		return append(buf, "\x1b[0m"...)
+	}
+	buf = append(buf, "\x1b["...)
+	buf = strconv.AppendInt(buf, int64(lvl.Color), 10)
+	return append(buf, 'm')
+}
+
+var emptyMetadata = map[string]interface{}{}
+
+// mergeMetadata merges the logger and entry metadata. If mustCopy is false and either map is
+// empty, the other one is returned as-is, so the result must not be modified.
+func mergeMetadata(m1, m2 map[string]interface{}, mustCopy bool) map[string]interface{} {
+	if !mustCopy {
+		if len(m2) == 0 && m1 != nil {
+			return m1
+		} else if len(m1) == 0 && m2 != nil {
+			return m2
+		} else if len(m1) == 0 && len(m2) == 0 {
+			return emptyMetadata
+		}
+	}
+	return reduceItem(m1, m2)
+}
+
+// AppendMetadataText appends the metadata to the buffer as space-separated key=value pairs sorted by key.
+//
+// Keys and string values are quoted with strconv.Quote if they're empty or contain spaces, quotes,
+// equals signs or non-printable characters. Maps, slices and structs are encoded as compact JSON.
+func AppendMetadataText(buf []byte, metadata map[string]interface{}) []byte {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = appendTextString(buf, key)
+		buf = append(buf, '=')
+		buf = appendTextValue(buf, metadata[key])
+	}
+	return buf
+}
+
+func appendTextValue(buf []byte, value interface{}) []byte {
+	switch typedValue := value.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendTextString(buf, typedValue)
+	case bool:
+		return strconv.AppendBool(buf, typedValue)
+	case int:
+		return strconv.AppendInt(buf, int64(typedValue), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(typedValue), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(typedValue), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(typedValue), 10)
+	case int64:
+		return strconv.AppendInt(buf, typedValue, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(typedValue), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(typedValue), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(typedValue), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(typedValue), 10)
+	case uint64:
+		return strconv.AppendUint(buf, typedValue, 10)
+	case float32:
+		return strconv.AppendFloat(buf, float64(typedValue), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(buf, typedValue, 'g', -1, 64)
+	case json.Number:
+		return append(buf, typedValue...)
+	}
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Ptr && reflectValue.IsNil() {
+		return append(buf, "null"...)
+	}
+	switch typedValue := value.(type) {
+	case error:
+		return appendTextString(buf, callStringMethod("Error", typedValue.Error))
+	case fmt.Stringer:
+		return appendTextString(buf, callStringMethod("String", typedValue.String))
+	}
+	switch reflectValue.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Ptr:
+		if data, err := json.Marshal(value); err == nil {
+			return append(buf, data...)
+		}
+	}
+	return appendTextString(buf, fmt.Sprint(value))
+}
+
+// callStringMethod calls an Error or String method. Like fmt, a panic in the method is written
+// into the output instead of crashing the logging call.
+func callStringMethod(name string, fn func() string) (str string) {
+	defer func() {
+		if err := recover(); err != nil {
+			str = fmt.Sprintf("%%!v(PANIC=%s method: %v)", name, err)
+		}
+	}()
+	return fn()
+}
+
+// appendTextString appends the string to the buffer, quoting it if it can't be safely written as-is.
+func appendTextString(buf []byte, str string) []byte {
+	if needsQuoting(str) {
+		return strconv.AppendQuote(buf, str)
+	}
+	return append(buf, str...)
+}
+
+func needsQuoting(str string) bool {
+	if len(str) == 0 || str[0] == '{' || str[0] == '[' {
+		return true
+	}
+	for i := 0; i < len(str); {
+		if b := str[i]; b < utf8.RuneSelf {
+			if b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"testing"
+	"time"
+)
+
+func newBenchLine() logLine {
+	entry := &Entry{
+		Time:    time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:   LevelInfo,
+		Module:  "bridge/sync",
+		Message: "Handled sync response with 5 events",
+		Metadata: map[string]interface{}{
+			"user_id": "@meow:example.com",
+			"count":   5,
+			"took":    12.5,
+		},
+	}
+	return newLogLine("2006-01-02 15:04:05", entry)
+}
+
+func benchmarkEncode(b *testing.B, format outputFormat) {
+	line := newBenchLine()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := getLineBuffer()
+		if _, err := buf.encode(line, LevelInfo, format); err != nil {
+			b.Fatal(err)
+		}
+		buf.release()
+	}
+}
+
+func BenchmarkEncodeText(b *testing.B) {
+	benchmarkEncode(b, outputFormat{})
+}
+
+func BenchmarkEncodeTextColor(b *testing.B) {
+	benchmarkEncode(b, outputFormat{color: true})
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	benchmarkEncode(b, outputFormat{json: true})
+}
+
+func benchmarkFileOutput(b *testing.B, json bool) {
+	log := newQuietLogger()
+	log.JSONFile = json
+	log.SetWriter(openTestFile(b, "bench.log"))
+	defer log.Close()
+	sub := log.Subm("bench", map[string]interface{}{"user_id": "@meow:example.com"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sub.Infofln("Handled sync response with %d events", 5)
+	}
+}
+
+func BenchmarkFileText(b *testing.B) {
+	benchmarkFileOutput(b, false)
+}
+
+func BenchmarkFileJSON(b *testing.B) {
+	benchmarkFileOutput(b, true)
+}
+
+func TestEncodeText(t *testing.T) {
+	line := newBenchLine()
+	buf := getLineBuffer()
+	defer buf.release()
+	data, err := buf.encode(line, LevelInfo, outputFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[2023-01-02 15:04:05] [bridge/sync/INFO] Handled sync response with 5 events count=5 took=12.5 user_id=@meow:example.com\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+type testStringer struct {
+	name string
+}
+
+func (ts *testStringer) String() string {
+	return ts.name
+}
+
+type testError struct{}
+
+func (*testError) Error() string {
+	panic("meow")
+}
+
+type panickingStringer struct{}
+
+func (panickingStringer) String() string {
+	panic("woof")
+}
+
+func TestAppendTextValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"Nil", nil, "null"},
+		{"Stringer", &testStringer{"meow"}, "meow"},
+		{"NilStringer", (*testStringer)(nil), "null"},
+		{"NilError", (*testError)(nil), "null"},
+		{"PanickingError", &testError{}, `"%!v(PANIC=Error method: meow)"`},
+		{"PanickingStringer", panickingStringer{}, `"%!v(PANIC=String method: woof)"`},
+		{"NilMap", map[string]int(nil), "null"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if encoded := string(appendTextValue(nil, test.value)); encoded != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, encoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// FileErrorPolicy configures what BasicLogger does when writing to the log file fails.
+type FileErrorPolicy struct {
+	// Callback is called after every failed write. It must not log to the same logger's file
+	// synchronously, as the write would most likely fail again.
+	Callback func(err error)
+	// Fallback receives lines that couldn't be written to the log file, e.g. os.Stderr or a secondary file.
+	// If nil, the lines are only written to the other outputs.
+	Fallback io.Writer
+	// ReportInterval is the minimum time between error reports written to stderr.
+	// Errors in between are counted and included in the next report. Zero reports every error.
+	ReportInterval time.Duration
+	// ReopenInterval is the minimum time between attempts to reopen the log file after a failed write.
+	// Zero disables reopening.
+	ReopenInterval time.Duration
+}
+
+type fileErrorState struct {
+	failing          bool
+	lastReopen       time.Time
+	lastReport       time.Time
+	suppressedErrors int
+}
+
+// reopenFile replaces the current writer with a newly opened handle to the same path.
+// The caller must hold writerLock.
+func (log *BasicLogger) reopenFile() error {
+	log.fileErrors.lastReopen = time.Now()
+	writer, err := os.OpenFile(log.writer.Name(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, log.FileMode)
+	if err != nil {
+		return err
+	}
+	_ = log.writer.Close()
+	log.writer = writer
+	return nil
+}
+
+func (log *BasicLogger) writeFallback(fallback io.Writer, data []byte) {
+	if fallback == nil {
+		return
+	} else if fallback == os.Stderr {
+		log.StderrLock.Lock()
+		defer log.StderrLock.Unlock()
+	}
+	_, _ = fallback.Write(data)
+}
+
+// writeFile writes the given line to the log file, handling errors according to FileErrorPolicy.
+func (log *BasicLogger) writeFile(data []byte) (n int, err error) {
+	var report string
+	log.writerLock.Lock()
+	if log.writer == nil {
+		// The file was closed after Raw checked for it
+		log.writerLock.Unlock()
+		return 0, nil
+	}
+	policy := log.FileErrorPolicy
+	n, err = log.writer.Write(data)
+	state := &log.fileErrors
+	if err != nil && policy.ReopenInterval > 0 && time.Since(state.lastReopen) >= policy.ReopenInterval {
+		if reopenErr := log.reopenFile(); reopenErr == nil {
+			n, err = log.writer.Write(data)
+		}
+	}
+	if err != nil {
+		state.failing = true
+		log.writeFallback(policy.Fallback, data)
+		if policy.ReportInterval <= 0 || time.Since(state.lastReport) >= policy.ReportInterval {
+			report = "Failed to write to log file: " + err.Error()
+			if state.suppressedErrors > 0 {
+				report += fmt.Sprintf(" (%d similar errors suppressed)", state.suppressedErrors)
+			}
+			state.lastReport = time.Now()
+			state.suppressedErrors = 0
+		} else {
+			state.suppressedErrors++
+		}
+	} else if state.failing {
+		state.failing = false
+		report = "Writing to log file succeeded again"
+		if state.suppressedErrors > 0 {
+			report += fmt.Sprintf(" (%d errors suppressed since last report)", state.suppressedErrors)
+		}
+		state.suppressedErrors = 0
+	}
+	log.writerLock.Unlock()
+
+	if len(report) > 0 {
+		log.StderrLock.Lock()
+		_, _ = os.Stderr.WriteString(report)
+		_, _ = os.Stderr.WriteString("\n")
+		log.StderrLock.Unlock()
+	}
+	if err != nil && policy.Callback != nil {
+		policy.Callback(err)
+	}
+	return
+}
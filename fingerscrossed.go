@@ -0,0 +1,198 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultFingersCrossedSize is the default maximum number of entries held by a FingersCrossedLogger.
+const DefaultFingersCrossedSize = 1000
+
+type bufferedEntry struct {
+	time     time.Time
+	level    Level
+	metadata map[string]interface{}
+	module   string
+	message  string
+}
+
+type entryBuffer struct {
+	lock      sync.Mutex
+	topLevel  *BasicLogger
+	trigger   Level
+	maxSize   int
+	entries   []bufferedEntry
+	dropped   int
+	triggered bool
+	ended     bool
+	// pending holds entries waiting to be written after the buffer has been triggered.
+	// They're written outside the lock by whichever call set writing, so that hooks and sinks
+	// can log through the same scope without deadlocking.
+	pending []bufferedEntry
+	writing bool
+}
+
+// captures checks if entries with the given level are taken by the buffer instead of the normal level filters.
+func (buf *entryBuffer) captures(level Level) bool {
+	buf.lock.Lock()
+	defer buf.lock.Unlock()
+	return !buf.ended && level.Severity >= LevelDebug.Severity
+}
+
+// add buffers the entry or writes it directly if the buffer has been triggered.
+// It returns false if the buffer has ended and the entry should be written normally.
+func (buf *entryBuffer) add(log *Sublogger, ts time.Time, level Level, message string) bool {
+	if ts.IsZero() {
+		ts = buf.topLevel.now()
+	}
+	entry := bufferedEntry{ts, level, log.metadata, log.Module, message}
+	buf.lock.Lock()
+	if buf.ended {
+		buf.lock.Unlock()
+		return false
+	}
+	if !buf.triggered && level.Severity >= buf.trigger.Severity {
+		buf.triggered = true
+		buf.queueBuffered()
+	}
+	if buf.triggered {
+		buf.pending = append(buf.pending, entry)
+		buf.writePending()
+		return true
+	}
+	if len(buf.entries) >= buf.maxSize {
+		buf.entries[0] = bufferedEntry{}
+		buf.entries = buf.entries[1:]
+		buf.dropped++
+	}
+	buf.entries = append(buf.entries, entry)
+	buf.lock.Unlock()
+	return true
+}
+
+// queueBuffered moves all buffered entries to the pending list. The caller must hold the lock.
+func (buf *entryBuffer) queueBuffered() {
+	if buf.dropped > 0 && len(buf.entries) > 0 {
+		first := buf.entries[0]
+		buf.pending = append(buf.pending, bufferedEntry{
+			first.time, LevelWarn, first.metadata, first.module,
+			fmt.Sprintf("Buffer was full, dropped %d earlier entries", buf.dropped),
+		})
+	}
+	buf.pending = append(buf.pending, buf.entries...)
+	buf.entries = nil
+	buf.dropped = 0
+}
+
+// writePending writes the pending entries in order, unless another call is already doing it.
+// The caller must hold the lock, which is released before returning.
+func (buf *entryBuffer) writePending() {
+	if buf.writing {
+		buf.lock.Unlock()
+		return
+	}
+	buf.writing = true
+	for len(buf.pending) > 0 {
+		entries := buf.pending
+		buf.pending = nil
+		buf.writeUnlocked(entries)
+	}
+	buf.writing = false
+	buf.lock.Unlock()
+}
+
+// writeUnlocked writes the entries with the lock released and takes the lock again before returning.
+// If writing an entry panics, the entries after it are put back in front of the pending list and the
+// lock is left released with writing reset, so the next entry of the scope writes them.
+func (buf *entryBuffer) writeUnlocked(entries []bufferedEntry) {
+	buf.lock.Unlock()
+	i := 0
+	defer func() {
+		buf.lock.Lock()
+		if i < len(entries) {
+			remaining := make([]bufferedEntry, 0, len(entries)-i-1+len(buf.pending))
+			remaining = append(remaining, entries[i+1:]...)
+			buf.pending = append(remaining, buf.pending...)
+			buf.writing = false
+			buf.lock.Unlock()
+		}
+	}()
+	for ; i < len(entries); i++ {
+		entry := entries[i]
+		buf.topLevel.raw(entry.time, entry.level, entry.metadata, entry.module, entry.message, true)
+	}
+}
+
+// FingersCrossedLogger is a Sublogger that holds all entries in memory until one at or above the
+// trigger level is logged. The buffered entries are then written with their original timestamps,
+// followed by all later entries of the scope. If End is called before that, the buffer is discarded.
+//
+// Entries are captured at all levels regardless of PrintLevel and ModuleLevels. When triggered, they're
+// written to the log file and sinks past ModuleLevels, so a failure comes with its full debug context,
+// but the console still only shows entries at or above its usual level.
+// Subloggers created from a FingersCrossedLogger share its buffer.
+type FingersCrossedLogger struct {
+	*Sublogger
+}
+
+// FingersCrossed creates a buffered scope on top of this Sublogger. The buffer holds at most
+// DefaultFingersCrossedSize entries, older entries are dropped when it's full.
+func (log *Sublogger) FingersCrossed(trigger Level) *FingersCrossedLogger {
+	return log.FingersCrossedSize(trigger, DefaultFingersCrossedSize)
+}
+
+// FingersCrossedSize creates a buffered scope that holds at most maxSize entries.
+func (log *Sublogger) FingersCrossedSize(trigger Level, maxSize int) *FingersCrossedLogger {
+	if maxSize <= 0 {
+		maxSize = DefaultFingersCrossedSize
+	}
+	return &FingersCrossedLogger{&Sublogger{
+		topLevel:     log.topLevel,
+		parent:       log,
+		Module:       log.Module,
+		DefaultLevel: log.DefaultLevel,
+		metadata:     log.metadata,
+		buffer:       &entryBuffer{topLevel: log.topLevel, trigger: trigger, maxSize: maxSize},
+	}}
+}
+
+// FingersCrossed creates a buffered scope on top of the default Sublogger. See FingersCrossedLogger.
+func (log *BasicLogger) FingersCrossed(trigger Level) *FingersCrossedLogger {
+	return log.Sub("").(*Sublogger).FingersCrossed(trigger)
+}
+
+// Triggered checks if the trigger level has been reached and the entries are being written.
+func (log *FingersCrossedLogger) Triggered() bool {
+	log.buffer.lock.Lock()
+	defer log.buffer.lock.Unlock()
+	return log.buffer.triggered
+}
+
+// Trigger writes the buffered entries and passes through all later ones, as if an entry at the trigger level had been logged.
+func (log *FingersCrossedLogger) Trigger() {
+	log.buffer.lock.Lock()
+	if log.buffer.ended || log.buffer.triggered {
+		log.buffer.lock.Unlock()
+		return
+	}
+	log.buffer.triggered = true
+	log.buffer.queueBuffered()
+	log.buffer.writePending()
+}
+
+// End ends the scope. If the trigger level wasn't reached, the buffered entries are discarded.
+// Entries logged after End are written normally.
+func (log *FingersCrossedLogger) End() {
+	log.buffer.lock.Lock()
+	log.buffer.ended = true
+	log.buffer.entries = nil
+	log.buffer.lock.Unlock()
+}
@@ -0,0 +1,134 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFingersCrossedTestLogger(t *testing.T) (*BasicLogger, *os.File) {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	log.PrintLevel = LevelInfo.Severity
+	file := openTestFile(t, "fingerscrossed.log")
+	log.SetWriter(file)
+	return log, file
+}
+
+func readTestFile(t *testing.T, file *os.File) string {
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFingersCrossedTriggered(t *testing.T) {
+	log, file := newFingersCrossedTestLogger(t)
+	defer log.Close()
+	stdout := captureStdout(t, func() {
+		scope := log.FingersCrossed(LevelWarn)
+		scope.Debugln("Debug context")
+		scope.Infoln("Info context")
+		if scope.Triggered() {
+			t.Error("Scope triggered too early")
+		}
+		scope.Warnln("Something failed")
+		scope.Debugln("Debug after trigger")
+		scope.End()
+	})
+	fileData := readTestFile(t, file)
+	for _, expected := range []string{"Debug context", "Info context", "Something failed", "Debug after trigger"} {
+		if !strings.Contains(fileData, expected) {
+			t.Errorf("Expected %q in log file, got %q", expected, fileData)
+		}
+	}
+	if strings.Index(fileData, "Debug context") > strings.Index(fileData, "Something failed") {
+		t.Error("Buffered entries were written after the trigger entry")
+	}
+	if strings.Contains(stdout, "Debug") {
+		t.Errorf("Debug entries were printed to the console: %q", stdout)
+	} else if !strings.Contains(stdout, "Info context") || !strings.Contains(stdout, "Something failed") {
+		t.Errorf("Expected info and warn entries on the console, got %q", stdout)
+	}
+}
+
+func TestFingersCrossedDiscarded(t *testing.T) {
+	log, file := newFingersCrossedTestLogger(t)
+	defer log.Close()
+	stdout := captureStdout(t, func() {
+		scope := log.FingersCrossed(LevelError)
+		scope.Infoln("Buffered info")
+		scope.End()
+		scope.Infoln("After end")
+	})
+	if fileData := readTestFile(t, file); strings.Contains(fileData, "Buffered info") || !strings.Contains(fileData, "After end") {
+		t.Errorf("Unexpected log file contents %q", fileData)
+	} else if strings.Contains(stdout, "Buffered info") {
+		t.Errorf("Discarded entry was printed: %q", stdout)
+	}
+}
+
+func TestFingersCrossedHookLogsToScope(t *testing.T) {
+	log, file := newFingersCrossedTestLogger(t)
+	defer log.Close()
+	scope := log.FingersCrossed(LevelWarn)
+	log.AddHookFunc(func(entry *Entry) bool {
+		if entry.Message == "Something failed" {
+			scope.Sub("hook").Infoln("Logged from hook")
+		}
+		return true
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		captureStdout(t, func() {
+			scope.Debugln("Debug context")
+			scope.Warnln("Something failed")
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Logging from a hook through the same scope deadlocked")
+	}
+	if fileData := readTestFile(t, file); !strings.Contains(fileData, "Logged from hook") {
+		t.Errorf("Expected hook entry in log file, got %q", fileData)
+	}
+}
+
+func TestFingersCrossedPanicWhileWriting(t *testing.T) {
+	log, file := newFingersCrossedTestLogger(t)
+	defer log.Close()
+	log.PrintLevel = LevelFatal.Severity + 1
+	scope := log.FingersCrossed(LevelWarn)
+	log.AddHookFunc(func(entry *Entry) bool {
+		if entry.Message == "Panicking context" {
+			panic("meow")
+		}
+		return true
+	})
+	scope.Infoln("Panicking context")
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "meow" {
+				t.Errorf("Expected the hook panic to propagate, got %v", recovered)
+			}
+		}()
+		scope.Warnln("Something failed")
+	}()
+	scope.Warnln("After panic")
+	fileData := readTestFile(t, file)
+	if !strings.Contains(fileData, "Something failed") || !strings.Contains(fileData, "After panic") {
+		t.Errorf("Expected entries after the panic to be written, got %q", fileData)
+	} else if strings.Index(fileData, "Something failed") > strings.Index(fileData, "After panic") {
+		t.Errorf("Entries left over from the panic were written out of order: %q", fileData)
+	}
+}
@@ -0,0 +1,130 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Formatter encodes entries for the file or console output.
+type Formatter interface {
+	// Format appends the encoded entry including a trailing newline to buf and returns the extended buffer.
+	Format(buf []byte, entry *Entry) ([]byte, error)
+}
+
+// FormatterFunc is a function that implements Formatter.
+type FormatterFunc func(buf []byte, entry *Entry) ([]byte, error)
+
+func (fn FormatterFunc) Format(buf []byte, entry *Entry) ([]byte, error) {
+	return fn(buf, entry)
+}
+
+// TextFormatter is the default text layout, e.g. `[15:04:05 02.01.2006] [module/INFO] Message key=value`.
+type TextFormatter struct {
+	TimeFormat string
+	// Color wraps the line in the escape codes of the entry level.
+	Color bool
+	// MetadataLine renders metadata on an indented second line.
+	MetadataLine bool
+	// Policy controls how newlines and control characters in messages are written.
+	Policy TextPolicy
+}
+
+func (tf *TextFormatter) Format(buf []byte, entry *Entry) ([]byte, error) {
+	line := newLogLine(tf.TimeFormat, entry)
+	line.policy = tf.Policy
+	return line.appendTextLine(buf, entry.Level, tf.Color, tf.MetadataLine), nil
+}
+
+// JSONFormatter encodes entries in the same JSON format as JSONFile and JSONStdout.
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(buf []byte, entry *Entry) ([]byte, error) {
+	lb := getLineBuffer()
+	defer lb.release()
+	data, err := lb.encodeJSON(newLogLine("", entry))
+	return append(buf, data...), err
+}
+
+// newLogLine converts the entry into the internal representation used by the builtin formats.
+func newLogLine(timeFormat string, entry *Entry) logLine {
+	line := logLine{
+		timeFormat: timeFormat,
+		caller:     entry.Caller,
+		Command:    "log",
+		Time:       entry.Time,
+		Level:      entry.Level.Name,
+		Module:     entry.Module,
+		Message:    entry.Message,
+		Metadata:   entry.Metadata,
+	}
+	if entry.Caller != nil {
+		line.Caller = formatCaller(entry.Caller)
+	}
+	return line
+}
+
+// entry converts the line back into an Entry with the given level.
+func (ll *logLine) entry(level Level) Entry {
+	return Entry{ll.Time, level, ll.Module, ll.Message, ll.Metadata, ll.caller}
+}
+
+// formatCaller formats the frame as the file name with its parent directory and the line number.
+func formatCaller(frame *runtime.Frame) string {
+	file := frame.File
+	if slash := strings.LastIndexByte(file, '/'); slash > 0 {
+		if parentSlash := strings.LastIndexByte(file[:slash], '/'); parentSlash >= 0 {
+			file = file[parentSlash+1:]
+		}
+	} else {
+		file = filepath.Base(file)
+	}
+	return file + ":" + strconv.Itoa(frame.Line)
+}
+
+var loggerPackage = reflect.TypeOf(BasicLogger{}).PkgPath()
+
+// findCaller returns the first stack frame outside the maulogger package.
+func findCaller() *runtime.Frame {
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, loggerPackage+".") {
+			return &frame
+		} else if !more {
+			return nil
+		}
+	}
+}
+
+// SetFileFormatter sets a custom formatter for the log file. Nil restores the builtin format selected by JSONFile.
+func (log *BasicLogger) SetFileFormatter(formatter Formatter) {
+	log.configLock.Lock()
+	log.FileFormatter = formatter
+	log.configLock.Unlock()
+}
+
+// SetStdoutFormatter sets a custom formatter for stdout/stderr. Nil restores the builtin format selected by JSONStdout.
+func (log *BasicLogger) SetStdoutFormatter(formatter Formatter) {
+	log.configLock.Lock()
+	log.StdoutFormatter = formatter
+	log.configLock.Unlock()
+}
+
+// SetReportCaller changes whether the caller of each logging function is included in entries.
+func (log *BasicLogger) SetReportCaller(enabled bool) {
+	log.configLock.Lock()
+	log.ReportCaller = enabled
+	log.configLock.Unlock()
+}
@@ -6,6 +6,8 @@ require (
 	github.com/rs/zerolog v1.29.0
 	github.com/tidwall/gjson v1.14.4
 	github.com/tidwall/sjson v1.2.5
+	go.opentelemetry.io/otel/trace v1.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -13,5 +15,6 @@ require (
 	github.com/mattn/go-isatty v0.0.14 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
+	go.opentelemetry.io/otel v1.14.0 // indirect
 	golang.org/x/sys v0.0.0-20210927094055-39ccf1dd6fa6 // indirect
 )
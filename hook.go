@@ -0,0 +1,79 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Entry is a single log entry before it's written to any output.
+type Entry struct {
+	Time     time.Time
+	Level    Level
+	Module   string
+	Message  string
+	Metadata map[string]interface{}
+	// Caller is the location of the logging call. It's only set if BasicLogger.ReportCaller is enabled.
+	Caller *runtime.Frame
+}
+
+// HookFunc is called for each log entry before it reaches any output.
+// The entry can be modified in place. Returning false drops the entry.
+type HookFunc func(entry *Entry) bool
+
+// Hook is a HookFunc with optional filters for which entries it runs on.
+type Hook struct {
+	// MinLevel is the minimum severity of entries the hook runs on.
+	MinLevel int
+	// Modules is a list of module names the hook runs on. Submodules of the listed modules are
+	// included. If empty, the hook runs on all modules.
+	Modules []string
+	Func    HookFunc
+}
+
+func (hook *Hook) matches(entry *Entry) bool {
+	if entry.Level.Severity < hook.MinLevel {
+		return false
+	} else if len(hook.Modules) == 0 {
+		return true
+	}
+	for _, module := range hook.Modules {
+		if entry.Module == module || strings.HasPrefix(entry.Module, module+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddHook adds a hook that will be run on all future entries. Hooks are run in the order they were added.
+func (log *BasicLogger) AddHook(hook Hook) {
+	log.hookLock.Lock()
+	log.hooks = append(log.hooks, hook)
+	log.hasHooks.Store(true)
+	log.hookLock.Unlock()
+}
+
+// AddHookFunc adds a hook that runs on all entries regardless of level or module.
+func (log *BasicLogger) AddHookFunc(fn HookFunc) {
+	log.AddHook(Hook{MinLevel: LevelDebug.Severity, Func: fn})
+}
+
+// runHooks runs all matching hooks on the entry and returns false if any of them dropped it.
+func (log *BasicLogger) runHooks(entry *Entry) bool {
+	log.hookLock.Lock()
+	hooks := log.hooks
+	log.hookLock.Unlock()
+	for i := range hooks {
+		if hooks[i].matches(entry) && !hooks[i].Func(entry) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,308 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrBatchQueueFull is returned by HTTPSink when too many batches are waiting to be sent.
+var ErrBatchQueueFull = errors.New("log batch queue is full")
+
+// HTTPBatchFormat is the body format used by HTTPSink.
+type HTTPBatchFormat int
+
+const (
+	// HTTPBatchNDJSON sends batches as newline-delimited JSON, one line per entry.
+	HTTPBatchNDJSON HTTPBatchFormat = iota
+	// HTTPBatchJSONArray sends batches as a single JSON array of entries.
+	HTTPBatchJSONArray
+)
+
+// ContentType returns the HTTP content type for the batch format.
+func (format HTTPBatchFormat) ContentType() string {
+	switch format {
+	case HTTPBatchJSONArray:
+		return "application/json"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+// HTTPError is returned when the log collector responds with a non-2xx status code.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err HTTPError) Error() string {
+	return fmt.Sprintf("log collector responded with HTTP %d: %s", err.StatusCode, err.Body)
+}
+
+// HTTPSink is a Sink that collects log lines into batches and POSTs them to an HTTP endpoint.
+//
+// The exported fields can be changed after NewHTTPSink, but only before the first WriteLine call.
+type HTTPSink struct {
+	URL    string
+	Format HTTPBatchFormat
+	Gzip   bool
+	Header http.Header
+	Client *http.Client
+
+	// MaxBatchSize is the maximum number of lines in a single request.
+	MaxBatchSize int
+	// FlushInterval is the maximum time a line is buffered before being sent. Zero or less means the default of 5 seconds.
+	FlushInterval time.Duration
+	// MaxQueuedBatches is the number of full batches that can wait to be sent. If the queue is full,
+	// the next full batch is dropped and WriteLine returns a DroppedError wrapping ErrBatchQueueFull.
+	MaxQueuedBatches int
+	// MaxRetries is the number of times a failed request is retried before the batch is dropped.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnError is called from the background sender when a batch can't be delivered.
+	// By default, errors are written to stderr.
+	OnError func(err error)
+
+	batch     [][]byte
+	lock      sync.Mutex
+	queue     chan [][]byte
+	flush     chan chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	closed    bool
+}
+
+var _ Sink = (*HTTPSink)(nil)
+
+// NewHTTPSink creates a sink that sends batches of log lines to the given URL.
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		URL:              url,
+		Format:           HTTPBatchNDJSON,
+		Header:           make(http.Header),
+		Client:           http.DefaultClient,
+		MaxBatchSize:     500,
+		FlushInterval:    defaultHTTPFlushInterval,
+		MaxQueuedBatches: 16,
+		MaxRetries:       5,
+		MinBackoff:       1 * time.Second,
+		MaxBackoff:       1 * time.Minute,
+
+		flush: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+const defaultHTTPFlushInterval = 5 * time.Second
+
+func (sink *HTTPSink) start() {
+	queueSize := sink.MaxQueuedBatches
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	sink.queue = make(chan [][]byte, queueSize)
+	go sink.loop()
+}
+
+// WriteLine adds the line to the current batch. If the batch is full, it is queued to be sent.
+func (sink *HTTPSink) WriteLine(_ Level, data []byte) error {
+	sink.startOnce.Do(sink.start)
+	line := make([]byte, len(data))
+	copy(line, data)
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return ErrSinkClosed
+	}
+	sink.batch = append(sink.batch, line)
+	if len(sink.batch) < sink.MaxBatchSize {
+		return nil
+	}
+	batch := sink.batch
+	sink.batch = nil
+	select {
+	case sink.queue <- batch:
+		return nil
+	default:
+		return DroppedError{Count: len(batch), Reason: ErrBatchQueueFull}
+	}
+}
+
+func (sink *HTTPSink) takeBatch() [][]byte {
+	sink.lock.Lock()
+	batch := sink.batch
+	sink.batch = nil
+	sink.lock.Unlock()
+	return batch
+}
+
+func (sink *HTTPSink) loop() {
+	defer close(sink.done)
+	flushInterval := sink.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultHTTPFlushInterval
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case batch := <-sink.queue:
+			sink.sendBatch(batch)
+		case <-ticker.C:
+			sink.sendBatch(sink.takeBatch())
+		case flushed := <-sink.flush:
+			sink.drainQueue()
+			sink.sendBatch(sink.takeBatch())
+			close(flushed)
+		case <-sink.stop:
+			sink.drainQueue()
+			sink.sendBatch(sink.takeBatch())
+			return
+		}
+	}
+}
+
+func (sink *HTTPSink) drainQueue() {
+	for {
+		select {
+		case batch := <-sink.queue:
+			sink.sendBatch(batch)
+		default:
+			return
+		}
+	}
+}
+
+func (sink *HTTPSink) encodeBatch(batch [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.Writer = &buf
+	var gz *gzip.Writer
+	if sink.Gzip {
+		gz = gzip.NewWriter(&buf)
+		writer = gz
+	}
+	if sink.Format == HTTPBatchJSONArray {
+		_, _ = writer.Write([]byte{'['})
+		_, _ = writer.Write(bytes.Join(batch, []byte{','}))
+		_, _ = writer.Write([]byte{']'})
+	} else {
+		for _, line := range batch {
+			_, _ = writer.Write(line)
+			_, _ = writer.Write([]byte{'\n'})
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (sink *HTTPSink) sendBatch(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := sink.encodeBatch(batch)
+	if err != nil {
+		sink.reportError(err)
+		return
+	}
+	backoff := sink.MinBackoff
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = sink.post(body)
+		if err == nil {
+			return
+		} else if !retry || attempt >= sink.MaxRetries {
+			sink.reportError(fmt.Errorf("dropping batch of %d log lines: %w", len(batch), err))
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-sink.stop:
+			// Still try to deliver the batch while closing, but don't wait between attempts.
+		}
+		backoff *= 2
+		if backoff > sink.MaxBackoff {
+			backoff = sink.MaxBackoff
+		}
+	}
+}
+
+func (sink *HTTPSink) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range sink.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", sink.Format.ContentType())
+	if sink.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := sink.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, HTTPError{StatusCode: resp.StatusCode, Body: string(respBody)}
+}
+
+func (sink *HTTPSink) reportError(err error) {
+	if sink.OnError != nil {
+		sink.OnError(err)
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, "Failed to send logs to HTTP collector:", err)
+	}
+}
+
+// Flush sends all buffered lines and waits until they've been delivered or dropped.
+func (sink *HTTPSink) Flush() {
+	sink.startOnce.Do(sink.start)
+	flushed := make(chan struct{})
+	select {
+	case sink.flush <- flushed:
+		<-flushed
+	case <-sink.done:
+	}
+}
+
+// Close sends all buffered lines and stops the background sender.
+func (sink *HTTPSink) Close() error {
+	sink.lock.Lock()
+	if sink.closed {
+		sink.lock.Unlock()
+		return nil
+	}
+	sink.closed = true
+	sink.lock.Unlock()
+	sink.startOnce.Do(sink.start)
+	close(sink.stop)
+	<-sink.done
+	return nil
+}
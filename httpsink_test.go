@@ -0,0 +1,172 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receivedBatch struct {
+	header http.Header
+	body   string
+}
+
+type testCollector struct {
+	*httptest.Server
+	lock     sync.Mutex
+	batches  []receivedBatch
+	failures int
+}
+
+func newTestCollector(t *testing.T, failures int) *testCollector {
+	collector := &testCollector{failures: failures}
+	collector.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("Failed to read gzip body: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			reader = gz
+		}
+		body, _ := io.ReadAll(reader)
+		collector.lock.Lock()
+		defer collector.lock.Unlock()
+		if collector.failures > 0 {
+			collector.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		collector.batches = append(collector.batches, receivedBatch{r.Header.Clone(), string(body)})
+	}))
+	t.Cleanup(collector.Close)
+	return collector
+}
+
+func (collector *testCollector) received() []receivedBatch {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	return collector.batches
+}
+
+func writeTestLines(t *testing.T, sink *HTTPSink, lines ...string) {
+	for _, line := range lines {
+		if err := sink.WriteLine(LevelInfo, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Flush()
+}
+
+func TestHTTPSinkNDJSON(t *testing.T) {
+	collector := newTestCollector(t, 0)
+	sink := NewHTTPSink(collector.URL)
+	defer sink.Close()
+	sink.Header.Set("Authorization", "Bearer meow")
+	writeTestLines(t, sink, `{"n":1}`, `{"n":2}`)
+
+	batches := collector.received()
+	if len(batches) != 1 {
+		t.Fatalf("Expected 1 batch, got %d", len(batches))
+	} else if batches[0].body != "{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("Unexpected body %q", batches[0].body)
+	} else if contentType := batches[0].header.Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Unexpected content type %q", contentType)
+	} else if auth := batches[0].header.Get("Authorization"); auth != "Bearer meow" {
+		t.Errorf("Custom header not sent, got %q", auth)
+	}
+}
+
+func TestHTTPSinkJSONArrayGzip(t *testing.T) {
+	collector := newTestCollector(t, 0)
+	sink := NewHTTPSink(collector.URL)
+	defer sink.Close()
+	sink.Format = HTTPBatchJSONArray
+	sink.Gzip = true
+	writeTestLines(t, sink, `{"n":1}`, `{"n":2}`)
+
+	batches := collector.received()
+	if len(batches) != 1 {
+		t.Fatalf("Expected 1 batch, got %d", len(batches))
+	} else if batches[0].body != `[{"n":1},{"n":2}]` {
+		t.Errorf("Unexpected body %q", batches[0].body)
+	} else if contentType := batches[0].header.Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Unexpected content type %q", contentType)
+	}
+}
+
+func TestHTTPSinkBatchSize(t *testing.T) {
+	collector := newTestCollector(t, 0)
+	sink := NewHTTPSink(collector.URL)
+	defer sink.Close()
+	sink.MaxBatchSize = 2
+	writeTestLines(t, sink, `{"n":1}`, `{"n":2}`, `{"n":3}`)
+
+	batches := collector.received()
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(batches))
+	} else if batches[0].body != "{\"n\":1}\n{\"n\":2}\n" || batches[1].body != "{\"n\":3}\n" {
+		t.Errorf("Unexpected batches %q and %q", batches[0].body, batches[1].body)
+	}
+}
+
+func TestHTTPSinkRetry(t *testing.T) {
+	collector := newTestCollector(t, 2)
+	sink := NewHTTPSink(collector.URL)
+	defer sink.Close()
+	sink.MinBackoff = time.Millisecond
+	sink.OnError = func(err error) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	writeTestLines(t, sink, `{"n":1}`)
+
+	if batches := collector.received(); len(batches) != 1 || batches[0].body != "{\"n\":1}\n" {
+		t.Errorf("Expected batch to be delivered after retries, got %v", batches)
+	}
+}
+
+func TestHTTPSinkRetryLimit(t *testing.T) {
+	collector := newTestCollector(t, 10)
+	sink := NewHTTPSink(collector.URL)
+	defer sink.Close()
+	sink.MinBackoff = time.Millisecond
+	sink.MaxRetries = 1
+	var errs []error
+	sink.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	writeTestLines(t, sink, `{"n":1}`)
+
+	var httpErr HTTPError
+	if len(errs) != 1 || !errors.As(errs[0], &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected one HTTP 503 error, got %v", errs)
+	} else if len(collector.received()) != 0 {
+		t.Error("Expected batch to be dropped")
+	}
+}
+
+func TestHTTPSinkZeroFlushInterval(t *testing.T) {
+	collector := newTestCollector(t, 0)
+	sink := NewHTTPSink(collector.URL)
+	sink.FlushInterval = 0
+	writeTestLines(t, sink, `{"n":1}`)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	} else if len(collector.received()) != 1 {
+		t.Error("Expected batch to be delivered")
+	}
+}
@@ -0,0 +1,83 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+// LazyValue is a log argument or metadata value that is only computed if the entry is actually written.
+type LazyValue interface {
+	LogValue() interface{}
+}
+
+// LazyFunc is a function that implements LazyValue.
+type LazyFunc func() interface{}
+
+func (fn LazyFunc) LogValue() interface{} {
+	return fn()
+}
+
+// Lazy wraps a function into a LazyValue.
+func Lazy(fn func() interface{}) LazyValue {
+	return LazyFunc(fn)
+}
+
+func resolveLazyValue(value interface{}) (interface{}, bool) {
+	switch typedValue := value.(type) {
+	case LazyValue:
+		return typedValue.LogValue(), true
+	case func() string:
+		return typedValue(), true
+	case func() interface{}:
+		return typedValue(), true
+	default:
+		return value, false
+	}
+}
+
+// ResolveLazy evaluates all LazyValues and func() string/interface{} values in the given args.
+// The input slice is returned as-is if it doesn't contain any lazy values.
+func ResolveLazy(args []interface{}) []interface{} {
+	var resolved []interface{}
+	for i, arg := range args {
+		value, isLazy := resolveLazyValue(arg)
+		if !isLazy {
+			if resolved != nil {
+				resolved[i] = arg
+			}
+			continue
+		} else if resolved == nil {
+			resolved = make([]interface{}, len(args))
+			copy(resolved, args[:i])
+		}
+		resolved[i] = value
+	}
+	if resolved == nil {
+		return args
+	}
+	return resolved
+}
+
+// resolveLazyMetadata evaluates lazy metadata values.
+// The input map is returned as-is if it doesn't contain any lazy values.
+func resolveLazyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	var resolved map[string]interface{}
+	for key, value := range metadata {
+		value, isLazy := resolveLazyValue(value)
+		if !isLazy {
+			continue
+		} else if resolved == nil {
+			resolved = make(map[string]interface{}, len(metadata))
+			for origKey, origValue := range metadata {
+				resolved[origKey] = origValue
+			}
+		}
+		resolved[key] = value
+	}
+	if resolved == nil {
+		return metadata
+	}
+	return resolved
+}
@@ -9,6 +9,7 @@ package maulogger
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Level is the severity level of a log entry.
@@ -32,10 +33,7 @@ var (
 
 // GetColor gets the ANSI escape color code for the log level.
 func (lvl Level) GetColor() string {
-	if lvl.Color < 0 {
-		return "\x1b[0m"
-	}
-	return fmt.Sprintf("\x1b[%dm", lvl.Color)
+	return string(lvl.appendColor(nil))
 }
 
 // GetReset gets the ANSI escape reset code.
@@ -45,3 +43,20 @@ func (lvl Level) GetReset() string {
 	}
 	return "\x1b[0m"
 }
+
+var builtinLevels = []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+
+// ParseLevel finds the builtin level with the given name. The name is case-insensitive,
+// and "warning" is accepted as an alias for LevelWarn.
+func ParseLevel(name string) (Level, error) {
+	upperName := strings.ToUpper(strings.TrimSpace(name))
+	if upperName == "WARNING" {
+		upperName = LevelWarn.Name
+	}
+	for _, level := range builtinLevels {
+		if level.Name == upperName {
+			return level, nil
+		}
+	}
+	return Level{}, fmt.Errorf("unknown log level %q", name)
+}
@@ -0,0 +1,123 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"unicode/utf8"
+)
+
+// TruncationMarker is appended to messages and values that were cut to fit the size limits.
+const TruncationMarker = "…[truncated]"
+
+// Metadata fields added when something is truncated. Truncated metadata values get the
+// OriginalLengthSuffix field named after their key, e.g. "body_original_length".
+const (
+	MessageOriginalLengthKey = "message_original_length"
+	EntryOriginalLengthKey   = "entry_original_length"
+	OriginalLengthSuffix     = "_original_length"
+)
+
+// SizeLimits restricts the size of entries written to an output. All sizes are in bytes, zero means no limit.
+type SizeLimits struct {
+	// MaxMessage is the maximum length of the message.
+	MaxMessage int `json:"max_message,omitempty" yaml:"max_message,omitempty"`
+	// MaxValue is the maximum length of top-level string metadata values.
+	MaxValue int `json:"max_value,omitempty" yaml:"max_value,omitempty"`
+	// MaxEntry is the maximum size of the whole encoded entry including the trailing newline.
+	// Oversized entries have their message truncated, or their metadata dropped if the message is too short to absorb the excess.
+	MaxEntry int `json:"max_entry,omitempty" yaml:"max_entry,omitempty"`
+}
+
+// truncateString cuts the string to at most maxLength bytes including the marker without splitting characters.
+func truncateString(str string, maxLength int) string {
+	if len(str) <= maxLength {
+		return str
+	}
+	cut := maxLength - len(TruncationMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(str[cut]) {
+		cut--
+	}
+	return str[:cut] + TruncationMarker
+}
+
+func copyMetadata(metadata map[string]interface{}, extraSpace int) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata)+extraSpace)
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
+// apply truncates the message and metadata values of the line. The metadata map is copied if anything is changed.
+func (sl *SizeLimits) apply(line logLine) logLine {
+	copied := false
+	if sl.MaxMessage > 0 && len(line.Message) > sl.MaxMessage {
+		line.Metadata = copyMetadata(line.Metadata, 1)
+		copied = true
+		line.Metadata[MessageOriginalLengthKey] = len(line.Message)
+		line.Message = truncateString(line.Message, sl.MaxMessage)
+	}
+	if sl.MaxValue > 0 {
+		for key, value := range line.Metadata {
+			str, ok := value.(string)
+			if !ok || len(str) <= sl.MaxValue {
+				continue
+			} else if !copied {
+				line.Metadata = copyMetadata(line.Metadata, 1)
+				copied = true
+			}
+			line.Metadata[key] = truncateString(str, sl.MaxValue)
+			line.Metadata[key+OriginalLengthSuffix] = len(str)
+		}
+	}
+	return line
+}
+
+// maxFitAttempts is the number of times encodeLimited re-encodes an entry that exceeds MaxEntry.
+// Escaping can make the encoded message longer than the raw one, so the first cut isn't always enough.
+const maxFitAttempts = 4
+
+// encodeLimited encodes the line with the given format after applying the size limits.
+// If the entry is still too large after all attempts, the last encoding is returned as-is.
+func (lb *lineBuffer) encodeLimited(line logLine, level Level, format outputFormat, limits SizeLimits) ([]byte, error) {
+	line = limits.apply(line)
+	data, err := lb.encode(line, level, format)
+	if err != nil || limits.MaxEntry <= 0 || len(data) <= limits.MaxEntry {
+		return data, err
+	}
+	originalSize := len(data)
+	line.Metadata = copyMetadata(line.Metadata, 1)
+	line.Metadata[EntryOriginalLengthKey] = originalSize
+	for i := 0; i < maxFitAttempts; i++ {
+		data, err = lb.encode(line, level, format)
+		if err != nil || len(data) <= limits.MaxEntry {
+			return data, err
+		}
+		excess := len(data) - limits.MaxEntry
+		if excess < len(line.Message)-len(TruncationMarker) {
+			line.Message = truncateString(line.Message, len(line.Message)-excess)
+		} else if len(line.Metadata) > 1 {
+			line.Metadata = map[string]interface{}{EntryOriginalLengthKey: originalSize}
+		} else {
+			line.Message = truncateString(line.Message, 0)
+		}
+	}
+	return lb.encode(line, level, format)
+}
+
+// SetSizeLimits changes the size limits of the file, console and sink outputs.
+func (log *BasicLogger) SetSizeLimits(file, stdout, sink SizeLimits) {
+	log.configLock.Lock()
+	log.FileLimits = file
+	log.StdoutLimits = stdout
+	log.SinkLimits = sink
+	log.configLock.Unlock()
+}
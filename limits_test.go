@@ -0,0 +1,118 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncodeLimited(t *testing.T) {
+	longValue := strings.Repeat("v", 100)
+	tests := []struct {
+		name     string
+		message  string
+		metadata map[string]interface{}
+		json     bool
+		limits   SizeLimits
+		expected string
+		// contains and excludes are checked instead of expected for entries cut to fit MaxEntry
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "NoLimits",
+			message:  "Hello world",
+			metadata: map[string]interface{}{"user_id": "@meow"},
+			expected: "[15:04:05] [INFO] Hello world user_id=@meow\n",
+		},
+		{
+			name:     "MaxMessage",
+			message:  "Hello world, how are you?",
+			limits:   SizeLimits{MaxMessage: 17},
+			expected: "[15:04:05] [INFO] Hel…[truncated] message_original_length=25\n",
+		},
+		{
+			name:     "MaxMessageMultiByte",
+			message:  "ääääääääää",
+			limits:   SizeLimits{MaxMessage: 17},
+			expected: "[15:04:05] [INFO] ä…[truncated] message_original_length=20\n",
+		},
+		{
+			name:     "MaxValue",
+			message:  "Hello",
+			metadata: map[string]interface{}{"body": "0123456789abcdefghij", "count": 1234567890123},
+			limits:   SizeLimits{MaxValue: 18},
+			expected: "[15:04:05] [INFO] Hello body=0123…[truncated] body_original_length=20 count=1234567890123\n",
+		},
+		{
+			name:     "MaxEntryCutsMessage",
+			message:  strings.Repeat("m", 200),
+			metadata: map[string]interface{}{"user_id": "@meow"},
+			limits:   SizeLimits{MaxEntry: 120},
+			contains: []string{TruncationMarker, "entry_original_length=", "user_id=@meow"},
+		},
+		{
+			name:     "MaxEntryDropsMetadata",
+			message:  "Hello",
+			metadata: map[string]interface{}{"a": longValue, "b": longValue},
+			limits:   SizeLimits{MaxEntry: 100},
+			contains: []string{"Hello", "entry_original_length="},
+			excludes: []string{"a=", "b="},
+		},
+		{
+			name:     "MaxEntryEscapedJSON",
+			message:  strings.Repeat("\"\x01", 100),
+			json:     true,
+			limits:   SizeLimits{MaxEntry: 200},
+			contains: []string{TruncationMarker, `"entry_original_length":`},
+		},
+		{
+			name:     "MaxEntryControlChars",
+			message:  strings.Repeat("\x1b", 200),
+			limits:   SizeLimits{MaxEntry: 150},
+			contains: []string{TruncationMarker, "entry_original_length="},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := &Entry{
+				Time:     time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+				Level:    LevelInfo,
+				Message:  test.message,
+				Metadata: test.metadata,
+			}
+			buf := getLineBuffer()
+			defer buf.release()
+			data, err := buf.encodeLimited(newLogLine("15:04:05", entry), LevelInfo, outputFormat{json: test.json}, test.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output := string(data)
+			if len(test.expected) > 0 && output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			} else if test.limits.MaxEntry > 0 && len(data) > test.limits.MaxEntry {
+				t.Errorf("Expected at most %d bytes, got %d: %q", test.limits.MaxEntry, len(data), output)
+			} else if !utf8.Valid(data) {
+				t.Errorf("Truncation split a character: %q", output)
+			}
+			for _, part := range test.contains {
+				if !strings.Contains(output, part) {
+					t.Errorf("Expected output to contain %q, got %q", part, output)
+				}
+			}
+			for _, part := range test.excludes {
+				if strings.Contains(output, part) {
+					t.Errorf("Expected output not to contain %q, got %q", part, output)
+				}
+			}
+		})
+	}
+}
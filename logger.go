@@ -8,18 +8,23 @@
 package maulogger
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // LoggerFileFormat ...
 type LoggerFileFormat func(now string, i int) string
 
+// BasicLogger is the default Logger implementation that writes to stdout/stderr, a log file and sinks.
+//
+// The exported settings fields must only be assigned directly before the logger is used.
+// Use the Set* methods (or Reload) to change them while other goroutines may be logging.
 type BasicLogger struct {
 	PrintLevel         int
 	FlushLineThreshold int
@@ -29,19 +34,79 @@ type BasicLogger struct {
 	FileMode           os.FileMode
 	DefaultSub         Logger
 
+	// ConsoleDisabled stops all output to stdout/stderr, regardless of PrintLevel and ModuleLevels.
+	ConsoleDisabled bool
+
 	JSONFile   bool
 	JSONStdout bool
+	// TextPolicy controls how newlines and control characters in messages are written in text mode.
+	TextPolicy TextPolicy
+	// StdoutMetadataLine renders metadata on an indented second line in console text output
+	// instead of after the message.
+	StdoutMetadataLine bool
+
+	// ModuleLevels is the minimum severity for specific modules. The level of the closest parent
+	// module is used for submodules. Entries below the level are dropped from all outputs, and
+	// the level replaces PrintLevel for the console, so it can be lower than PrintLevel too.
+	ModuleLevels map[string]int
+
+	// FileRedactor, StdoutRedactor and SinkRedactor mask sensitive data before it's written to the
+	// respective output. Use SetRedactor to set the same redactor for all outputs, or SetFileRedactor,
+	// SetStdoutRedactor and SetSinkRedactor to change them individually.
+	FileRedactor   *Redactor
+	StdoutRedactor *Redactor
+	SinkRedactor   *Redactor
+
+	// FileLimits, StdoutLimits and SinkLimits restrict the size of entries written to the respective output.
+	FileLimits   SizeLimits
+	StdoutLimits SizeLimits
+	SinkLimits   SizeLimits
+
+	// FileErrorPolicy configures how failed writes to the log file are handled.
+	FileErrorPolicy FileErrorPolicy
+
+	// Metrics receives events about logging volume and write errors. Optional.
+	Metrics MetricsCollector
 
-	stdoutEncoder *json.Encoder
-	fileEncoder   *json.Encoder
+	// FileFormatter and StdoutFormatter replace the builtin text and JSON formats of the respective output.
+	// Entries at LevelError and above are still written to stderr when StdoutFormatter is set.
+	FileFormatter   Formatter
+	StdoutFormatter Formatter
+	// ReportCaller includes the file and line of the logging call in entries.
+	ReportCaller bool
+	// Clock is used for entry timestamps and log file names. Defaults to time.Now.
+	Clock Clock
+	// Location is the timezone used for TimeFormat and FileTimeFormat. Defaults to local time.
+	Location *time.Location
+	// AutoFields selects process information added to every entry.
+	AutoFields AutoFields
+
+	configLock    sync.RWMutex
+	reloadLock    sync.Mutex
+	appliedConfig *Config
 
 	writer     *os.File
 	writerLock sync.Mutex
+	// hasFile and hasSinks allow checking if there are any outputs without taking the locks.
+	hasFile    atomic.Bool
+	hasSinks   atomic.Bool
+	hasHooks   atomic.Bool
 	StdoutLock sync.Mutex
 	StderrLock sync.Mutex
 	lines      int
+	fileErrors fileErrorState
+
+	sinks       []Sink
+	configSinks []Sink
+	sinkLock    sync.Mutex
+	hooks       []Hook
+	hookLock    sync.Mutex
 
 	metadata map[string]interface{}
+
+	instanceID     string
+	instanceIDOnce sync.Once
+	sequence       atomic.Uint64
 }
 
 // Logger contains advanced logging functions
@@ -50,6 +115,15 @@ type Logger interface {
 	Subm(module string, metadata map[string]interface{}) Logger
 	WithDefaultLevel(level Level) Logger
 	GetParent() Logger
+	// Enabled checks if entries with the given level would be written anywhere.
+	// It can be used to skip computing expensive arguments for disabled levels.
+	Enabled(level Level) bool
+	// Begin starts a timed operation, see Operation.
+	Begin(name string, fields ...interface{}) *Operation
+	// Recover logs a panic with its stack and handles it according to the options. It must be called directly with defer.
+	Recover(opts RecoverOptions)
+	// Go runs the function in a new goroutine and logs any panic before it crashes the process.
+	Go(fn func())
 
 	Writer(level Level) io.WriteCloser
 
@@ -80,6 +154,17 @@ type Logger interface {
 	Fatalfln(message string, args ...interface{})
 }
 
+// TimedLogger is implemented by loggers that can log entries with an explicit timestamp.
+type TimedLogger interface {
+	Logger
+	LogAt(ts time.Time, level Level, parts ...interface{})
+}
+
+var (
+	_ TimedLogger = (*BasicLogger)(nil)
+	_ TimedLogger = (*Sublogger)(nil)
+)
+
 // Create a Logger
 func Createm(metadata map[string]interface{}) Logger {
 	var log = &BasicLogger{
@@ -91,6 +176,10 @@ func Createm(metadata map[string]interface{}) Logger {
 		FlushLineThreshold: 5,
 		lines:              0,
 		metadata:           metadata,
+		FileErrorPolicy: FileErrorPolicy{
+			ReportInterval: 1 * time.Minute,
+			ReopenInterval: 10 * time.Second,
+		},
 	}
 	log.DefaultSub = log.Sub("")
 	return log
@@ -101,8 +190,103 @@ func Create() Logger {
 }
 
 func (log *BasicLogger) EnableJSONStdout() {
+	log.configLock.Lock()
 	log.JSONStdout = true
-	log.stdoutEncoder = json.NewEncoder(os.Stdout)
+	log.configLock.Unlock()
+}
+
+// DisableJSONStdout switches stdout back to the colored text format.
+func (log *BasicLogger) DisableJSONStdout() {
+	log.configLock.Lock()
+	log.JSONStdout = false
+	log.configLock.Unlock()
+}
+
+// SetPrintLevel changes the minimum severity of entries printed to stdout/stderr.
+func (log *BasicLogger) SetPrintLevel(severity int) {
+	log.configLock.Lock()
+	log.PrintLevel = severity
+	log.configLock.Unlock()
+}
+
+// SetConsoleDisabled changes whether entries are printed to stdout/stderr at all.
+func (log *BasicLogger) SetConsoleDisabled(disabled bool) {
+	log.configLock.Lock()
+	log.ConsoleDisabled = disabled
+	log.configLock.Unlock()
+}
+
+// GetPrintLevel returns the minimum severity of entries printed to stdout/stderr.
+func (log *BasicLogger) GetPrintLevel() int {
+	log.configLock.RLock()
+	defer log.configLock.RUnlock()
+	return log.PrintLevel
+}
+
+// SetTimeFormat changes the timestamp format used in text output.
+func (log *BasicLogger) SetTimeFormat(format string) {
+	log.configLock.Lock()
+	log.TimeFormat = format
+	log.configLock.Unlock()
+}
+
+// SetJSONFile changes whether entries are written to the log file as JSON.
+func (log *BasicLogger) SetJSONFile(enabled bool) {
+	log.configLock.Lock()
+	log.JSONFile = enabled
+	log.configLock.Unlock()
+}
+
+// SetStdoutMetadataLine changes whether console text output renders metadata on a separate line.
+func (log *BasicLogger) SetStdoutMetadataLine(enabled bool) {
+	log.configLock.Lock()
+	log.StdoutMetadataLine = enabled
+	log.configLock.Unlock()
+}
+
+// SetModuleLevels replaces the per-module minimum severities. The map must not be modified afterwards.
+func (log *BasicLogger) SetModuleLevels(levels map[string]int) {
+	log.configLock.Lock()
+	log.ModuleLevels = levels
+	log.configLock.Unlock()
+}
+
+// SetMetrics changes the metrics collector.
+func (log *BasicLogger) SetMetrics(metrics MetricsCollector) {
+	log.configLock.Lock()
+	log.Metrics = metrics
+	log.configLock.Unlock()
+}
+
+// SetFileFormat changes the function used to build log file paths. It's used the next time OpenFile is called.
+func (log *BasicLogger) SetFileFormat(format LoggerFileFormat) {
+	log.configLock.Lock()
+	log.FileFormat = format
+	log.configLock.Unlock()
+}
+
+// SetFileTimeFormat changes the date format used in log file paths. It's used the next time OpenFile is called.
+func (log *BasicLogger) SetFileTimeFormat(format string) {
+	log.configLock.Lock()
+	log.FileTimeFormat = format
+	log.configLock.Unlock()
+}
+
+// SetFileMode changes the permissions used when creating log files.
+func (log *BasicLogger) SetFileMode(mode os.FileMode) {
+	log.configLock.Lock()
+	// FileMode is also read by reopenFile, which only holds writerLock
+	log.writerLock.Lock()
+	log.FileMode = mode
+	log.writerLock.Unlock()
+	log.configLock.Unlock()
+}
+
+// SetFileErrorPolicy changes how failed writes to the log file are handled.
+func (log *BasicLogger) SetFileErrorPolicy(policy FileErrorPolicy) {
+	log.writerLock.Lock()
+	log.FileErrorPolicy = policy
+	log.writerLock.Unlock()
 }
 
 func (log *BasicLogger) GetParent() Logger {
@@ -111,41 +295,79 @@ func (log *BasicLogger) GetParent() Logger {
 
 // SetWriter formats the given parts with fmt.Sprint and logs the result with the SetWriter level
 func (log *BasicLogger) SetWriter(w *os.File) {
+	log.writerLock.Lock()
 	log.writer = w
-	if log.JSONFile {
-		log.fileEncoder = json.NewEncoder(w)
-	}
+	log.hasFile.Store(w != nil)
+	log.fileErrors = fileErrorState{}
+	log.writerLock.Unlock()
+}
+
+func (log *BasicLogger) hasWriter() bool {
+	return log.hasFile.Load()
 }
 
 // OpenFile formats the given parts with fmt.Sprint and logs the result with the OpenFile level
 func (log *BasicLogger) OpenFile() error {
-	now := time.Now().Format(log.FileTimeFormat)
-	i := 1
-	for ; ; i++ {
-		if _, err := os.Stat(log.FileFormat(now, i)); os.IsNotExist(err) {
+	log.configLock.RLock()
+	fileTimeFormat, fileFormat, fileMode := log.FileTimeFormat, log.FileFormat, log.FileMode
+	now := currentTime(log.Clock, log.Location)
+	log.configLock.RUnlock()
+	writer, err := openLogFile(now, fileTimeFormat, fileFormat, fileMode)
+	if err != nil {
+		return err
+	}
+	log.SetWriter(writer)
+	return nil
+}
+
+// maxLogFileIndex is the highest file index openLogFile will try before giving up.
+const maxLogFileIndex = 10000
+
+func openLogFile(ts time.Time, fileTimeFormat string, fileFormat LoggerFileFormat, fileMode os.FileMode) (*os.File, error) {
+	now := ts.Format(fileTimeFormat)
+	path := fileFormat(now, 1)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
 			break
+		} else if i > maxLogFileIndex {
+			return nil, fmt.Errorf("more than %d log files already exist for %s", maxLogFileIndex, now)
 		}
+		next := fileFormat(now, i)
+		if next == path {
+			// The format doesn't include the index, so just append to the existing file.
+			break
+		}
+		path = next
 	}
-	writer, err := os.OpenFile(log.FileFormat(now, i), os.O_WRONLY|os.O_CREATE|os.O_APPEND, log.FileMode)
+	writer, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileMode)
 	if err != nil {
-		return err
+		return nil, err
 	} else if writer == nil {
-		return os.ErrInvalid
+		return nil, os.ErrInvalid
 	}
-	log.SetWriter(writer)
-	return nil
+	return writer, nil
 }
 
 // Close formats the given parts with fmt.Sprint and logs the result with the Close level
 func (log *BasicLogger) Close() error {
-	if log.writer != nil {
-		return log.writer.Close()
+	sinkErr := log.closeSinks()
+	log.writerLock.Lock()
+	writer := log.writer
+	log.writer = nil
+	log.hasFile.Store(false)
+	log.writerLock.Unlock()
+	if writer != nil {
+		if err := writer.Close(); err != nil && sinkErr == nil {
+			return err
+		}
 	}
-	return nil
+	return sinkErr
 }
 
 type logLine struct {
-	log *BasicLogger
+	timeFormat string
+	caller     *runtime.Frame
+	policy     TextPolicy
 
 	Command  string                 `json:"command"`
 	Time     time.Time              `json:"time"`
@@ -153,18 +375,11 @@ type logLine struct {
 	Module   string                 `json:"module"`
 	Message  string                 `json:"message"`
 	Metadata map[string]interface{} `json:"metadata"`
-}
-
-func (ll logLine) String() string {
-	if len(ll.Module) == 0 {
-		return fmt.Sprintf("[%s] [%s] %s", ll.Time.Format(ll.log.TimeFormat), ll.Level, ll.Message)
-	} else {
-		return fmt.Sprintf("[%s] [%s/%s] %s", ll.Time.Format(ll.log.TimeFormat), ll.Module, ll.Level, ll.Message)
-	}
+	Caller   string                 `json:"caller,omitempty"`
 }
 
 func reduceItem(m1, m2 map[string]interface{}) map[string]interface{} {
-	m3 := map[string]interface{}{}
+	m3 := make(map[string]interface{}, len(m1)+len(m2))
 
 	_merge := func(m map[string]interface{}) {
 		for ia, va := range m {
@@ -178,47 +393,203 @@ func reduceItem(m1, m2 map[string]interface{}) map[string]interface{} {
 	return m3
 }
 
+// settings is a snapshot of the runtime-tunable BasicLogger fields used by Raw.
+type settings struct {
+	printLevel      int
+	consoleDisabled bool
+	timeFormat      string
+	jsonFile        bool
+	jsonStdout      bool
+	metadataLine    bool
+	textPolicy      TextPolicy
+	moduleLevels    map[string]int
+	fileRedactor    *Redactor
+	stdoutRedactor  *Redactor
+	sinkRedactor    *Redactor
+	fileLimits      SizeLimits
+	stdoutLimits    SizeLimits
+	sinkLimits      SizeLimits
+	metrics         MetricsCollector
+	metadata        map[string]interface{}
+	fileFormatter   Formatter
+	stdoutFormatter Formatter
+	reportCaller    bool
+	clock           Clock
+	location        *time.Location
+	autoFields      AutoFields
+}
+
+func (log *BasicLogger) snapshot() settings {
+	log.configLock.RLock()
+	defer log.configLock.RUnlock()
+	return settings{
+		printLevel:      log.PrintLevel,
+		consoleDisabled: log.ConsoleDisabled,
+		timeFormat:      log.TimeFormat,
+		jsonFile:        log.JSONFile,
+		jsonStdout:      log.JSONStdout,
+		metadataLine:    log.StdoutMetadataLine,
+		textPolicy:      log.TextPolicy,
+		moduleLevels:    log.ModuleLevels,
+		fileRedactor:    log.FileRedactor,
+		stdoutRedactor:  log.StdoutRedactor,
+		sinkRedactor:    log.SinkRedactor,
+		fileLimits:      log.FileLimits,
+		stdoutLimits:    log.StdoutLimits,
+		sinkLimits:      log.SinkLimits,
+		metrics:         log.Metrics,
+		metadata:        log.metadata,
+		fileFormatter:   log.FileFormatter,
+		stdoutFormatter: log.StdoutFormatter,
+		reportCaller:    log.ReportCaller,
+		clock:           log.Clock,
+		location:        log.Location,
+		autoFields:      log.AutoFields,
+	}
+}
+
+// allows checks if an entry with the given level and module would be written to any output.
+// If the module has a ModuleLevels entry, it replaces printLevel in the settings.
+func (s *settings) allows(log *BasicLogger, level Level, module string) bool {
+	if minLevel, ok := s.moduleMinLevel(module); ok {
+		if level.Severity < minLevel {
+			return false
+		}
+		s.printLevel = minLevel
+	}
+	return s.prints(level) || log.hasFile.Load() || log.hasSinks.Load()
+}
+
+// prints checks if an entry with the given level should be printed to stdout/stderr.
+func (s *settings) prints(level Level) bool {
+	return !s.consoleDisabled && level.Severity >= s.printLevel
+}
+
+// Enabled checks if entries with the given level would be written to any output.
+// Hooks and metrics aren't run for entries that don't reach any output.
+func (log *BasicLogger) Enabled(level Level) bool {
+	return log.enabled(level, "")
+}
+
+func (log *BasicLogger) enabled(level Level, module string) bool {
+	log.configLock.RLock()
+	cfg := settings{printLevel: log.PrintLevel, consoleDisabled: log.ConsoleDisabled, moduleLevels: log.ModuleLevels}
+	log.configLock.RUnlock()
+	return cfg.allows(log, level, module)
+}
+
+// moduleMinLevel finds the ModuleLevels entry for the given module or its closest parent.
+func (s *settings) moduleMinLevel(module string) (int, bool) {
+	if len(s.moduleLevels) == 0 {
+		return 0, false
+	}
+	for {
+		if minLevel, ok := s.moduleLevels[module]; ok {
+			return minLevel, true
+		}
+		slash := strings.LastIndexByte(module, '/')
+		if slash < 0 {
+			return 0, false
+		}
+		module = module[:slash]
+	}
+}
+
+func (s *settings) countWriteError(output string, err error) {
+	if err != nil && s.metrics != nil {
+		s.metrics.WriteError(output, err)
+	}
+}
+
+func (s *settings) countDropped(level Level, module string, count int) {
+	if s.metrics != nil {
+		module = topLevelModule(module)
+		for i := 0; i < count; i++ {
+			s.metrics.EntryDropped(level, module)
+		}
+	}
+}
+
 // Raw formats the given parts with fmt.Sprint and logs the result with the Raw level
 func (log *BasicLogger) Raw(level Level, extraMetadata map[string]interface{}, module, origMessage string) {
-	message := logLine{log, "log", time.Now(), level.Name, module, strings.TrimSpace(origMessage), reduceItem(log.metadata, extraMetadata)}
+	log.raw(time.Time{}, level, extraMetadata, module, origMessage, false)
+}
 
-	if log.writer != nil {
-		log.writerLock.Lock()
-		var err error
-		if log.JSONFile {
-			err = log.fileEncoder.Encode(&message)
-		} else {
-			_, err = log.writer.WriteString(message.String())
-			_, _ = log.writer.WriteString("\n")
+// raw writes an entry. If force is true, the entry is written to the file and sinks regardless of
+// ModuleLevels, but is still only printed to the console if it's at or above the console level.
+func (log *BasicLogger) raw(ts time.Time, level Level, extraMetadata map[string]interface{}, module, origMessage string, force bool) {
+	cfg := log.snapshot()
+	if !cfg.allows(log, level, module) {
+		// Forced entries skip ModuleLevels, but are still dropped if no output would take them
+		if !force || (!cfg.prints(level) && !log.hasFile.Load() && !log.hasSinks.Load()) {
+			return
 		}
-		log.writerLock.Unlock()
-		if err != nil {
-			log.StderrLock.Lock()
-			_, _ = os.Stderr.WriteString("Failed to write to log file:")
-			_, _ = os.Stderr.WriteString(err.Error())
-			log.StderrLock.Unlock()
+	}
+	if ts.IsZero() {
+		ts = currentTime(cfg.clock, cfg.location)
+	} else {
+		ts = inLocation(ts, cfg.location)
+	}
+	hasHooks := log.hasHooks.Load()
+	hasAutoFields := cfg.autoFields.any()
+	entry := Entry{ts, level, module, strings.TrimSpace(origMessage), resolveLazyMetadata(mergeMetadata(cfg.metadata, extraMetadata, hasHooks || hasAutoFields)), nil}
+	if cfg.reportCaller {
+		entry.Caller = findCaller()
+	}
+	if hasHooks && !log.runHooks(&entry) {
+		if cfg.metrics != nil {
+			cfg.metrics.EntryDropped(entry.Level, topLevelModule(entry.Module))
+		}
+		return
+	}
+	if hasAutoFields {
+		if hasHooks {
+			// Hooks may have replaced the metadata with a map we don't own
+			entry.Metadata = copyMetadata(entry.Metadata, 5)
 		}
+		log.addAutoFields(entry.Metadata, cfg.autoFields)
 	}
+	level = entry.Level
+	message := newLogLine(cfg.timeFormat, &entry)
+	message.policy = cfg.textPolicy
+	if cfg.metrics != nil {
+		cfg.metrics.EntryLogged(level, topLevelModule(message.Module))
+	}
+
+	buf := getLineBuffer()
+	defer buf.release()
 
-	if level.Severity >= log.PrintLevel {
-		if log.JSONStdout {
-			log.StdoutLock.Lock()
-			_ = log.stdoutEncoder.Encode(&message)
-			log.StdoutLock.Unlock()
-		} else if level.Severity >= LevelError.Severity {
-			log.StderrLock.Lock()
-			_, _ = os.Stderr.WriteString(level.GetColor())
-			_, _ = os.Stderr.WriteString(message.String())
-			_, _ = os.Stderr.WriteString(level.GetReset())
-			_, _ = os.Stderr.WriteString("\n")
-			log.StderrLock.Unlock()
+	if log.hasWriter() {
+		fileFormat := outputFormat{formatter: cfg.fileFormatter, json: cfg.jsonFile}
+		data, err := buf.encodeLimited(cfg.fileRedactor.redact(message), level, fileFormat, cfg.fileLimits)
+		if err != nil {
+			log.reportError("Failed to encode log line for file:", err)
 		} else {
-			log.StdoutLock.Lock()
-			_, _ = os.Stdout.WriteString(level.GetColor())
-			_, _ = os.Stdout.WriteString(message.String())
-			_, _ = os.Stdout.WriteString(level.GetReset())
-			_, _ = os.Stdout.WriteString("\n")
-			log.StdoutLock.Unlock()
+			var n int
+			n, err = log.writeFile(data)
+			if cfg.metrics != nil {
+				cfg.metrics.FileBytesWritten(n)
+			}
+			cfg.countWriteError(OutputFile, err)
+		}
+	}
+
+	if log.hasSinks.Load() {
+		log.writeSinks(&cfg, buf, level, message)
+	}
+
+	if cfg.prints(level) {
+		output, lock, outputName := os.Stdout, &log.StdoutLock, OutputStdout
+		if level.Severity >= LevelError.Severity && (cfg.stdoutFormatter != nil || !cfg.jsonStdout) {
+			output, lock, outputName = os.Stderr, &log.StderrLock, OutputStderr
+		}
+		stdoutFormat := outputFormat{formatter: cfg.stdoutFormatter, json: cfg.jsonStdout, color: true, metadataLine: cfg.metadataLine}
+		data, err := buf.encodeLimited(cfg.stdoutRedactor.redact(message), level, stdoutFormat, cfg.stdoutLimits)
+		if err == nil {
+			lock.Lock()
+			_, err = output.Write(data)
+			lock.Unlock()
 		}
+		cfg.countWriteError(outputName, err)
 	}
 }
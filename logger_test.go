@@ -0,0 +1,173 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newQuietLogger creates a logger that doesn't print anything to the console.
+func newQuietLogger() *BasicLogger {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	log.PrintLevel = LevelFatal.Severity + 1
+	return log
+}
+
+func openTestFile(t testing.TB, name string) *os.File {
+	file, err := os.OpenFile(filepath.Join(t.TempDir(), name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// runConcurrently runs each function in a loop in its own goroutine until all of them have run the given number of times.
+func runConcurrently(iterations int, fns ...func(i int)) {
+	var wg sync.WaitGroup
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func(i int)) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				fn(i)
+			}
+		}(fn)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentSetWriterRawClose(t *testing.T) {
+	log := newQuietLogger()
+	log.SetWriter(openTestFile(t, "initial.log"))
+	files := make([]*os.File, 20)
+	for i := range files {
+		files[i] = openTestFile(t, fmt.Sprintf("%d.log", i))
+	}
+	sub := log.Sub("test")
+	runConcurrently(200,
+		func(i int) {
+			log.Raw(LevelInfo, map[string]interface{}{"i": i}, "test", "Raw entry")
+		},
+		func(i int) {
+			sub.Warnfln("Sublogger entry %d", i)
+		},
+		func(i int) {
+			if i%10 == 0 {
+				log.SetWriter(files[i/10])
+			}
+		},
+		func(i int) {
+			if i%50 == 0 {
+				_ = log.Close()
+			}
+		},
+		func(i int) {
+			log.SetPrintLevel(LevelFatal.Severity + 1 + i%2)
+			log.SetJSONFile(i%2 == 0)
+			log.SetTimeFormat(time.RFC3339)
+		},
+	)
+	_ = log.Close()
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+func TestConcurrentReloadAndReopen(t *testing.T) {
+	log := newQuietLogger()
+	log.SetFileErrorPolicy(FileErrorPolicy{ReportInterval: time.Hour, ReopenInterval: time.Nanosecond})
+	// Writes to a closed file fail, which makes the logger try to reopen it on every write.
+	// The directory is removed so that reopening fails too.
+	dir := filepath.Join(t.TempDir(), "removed")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(filepath.Join(dir, "reopen.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	_ = os.RemoveAll(dir)
+	log.SetWriter(file)
+	cfg := DefaultConfig()
+	cfg.Console.Disabled = true
+	runConcurrently(100,
+		func(i int) {
+			log.Infofln("Entry %d", i)
+		},
+		func(i int) {
+			if i%2 == 0 {
+				cfg.File.Mode = "0600"
+			} else {
+				cfg.File.Mode = "0640"
+			}
+			if err := log.Reload(cfg); err != nil {
+				t.Error(err)
+			}
+		},
+		func(i int) {
+			if i%2 == 0 {
+				log.SetFileMode(0600)
+			} else {
+				log.SetFileMode(0640)
+			}
+		},
+	)
+	_ = log.Close()
+}
+
+func TestConcurrentSinksAndHooks(t *testing.T) {
+	log := newQuietLogger()
+	runConcurrently(100,
+		func(i int) {
+			log.Debugfln("Entry %d", i)
+		},
+		func(i int) {
+			if i%10 == 0 {
+				log.AddSink(&discardSink{})
+			}
+		},
+		func(i int) {
+			if i%25 == 0 {
+				_ = log.Close()
+			}
+		},
+	)
+	_ = log.Close()
+}
+
+type discardSink struct{}
+
+func (*discardSink) WriteLine(Level, []byte) error { return nil }
+func (*discardSink) Close() error                  { return nil }
+
+func BenchmarkDisabledDebugf(b *testing.B) {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	sub := log.Sub("bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sub.Debugfln("Disabled entry %d with %s", 42, "an argument")
+	}
+}
+
+func TestDisabledDebugfDoesNotAllocate(t *testing.T) {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	sub := log.Sub("bench")
+	allocs := testing.AllocsPerRun(100, func() {
+		sub.Debugfln("Disabled entry %d with %s", 5, "an argument")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected suppressed debug call not to allocate, got %.1f allocs", allocs)
+	}
+}
@@ -32,8 +32,12 @@ func (m MauZeroLog) Sub(module string) maulogger.Logger {
 	return m.Subm(module, map[string]interface{}{})
 }
 
+// Subm creates a sub-logger with the given module and metadata. zerolog encodes context fields
+// immediately, so lazy metadata values are evaluated once here rather than for every entry.
 func (m MauZeroLog) Subm(module string, metadata map[string]interface{}) maulogger.Logger {
-	if m.mod != "" {
+	if module == "" {
+		module = m.mod
+	} else if m.mod != "" {
 		module = fmt.Sprintf("%s/%s", m.mod, module)
 	}
 	var orig zerolog.Logger
@@ -45,7 +49,7 @@ func (m MauZeroLog) Subm(module string, metadata map[string]interface{}) maulogg
 	if len(metadata) > 0 {
 		with := m.orig.With()
 		for key, value := range metadata {
-			with = with.Interface(key, value)
+			with = with.Interface(key, maulogger.ResolveLazy([]interface{}{value})[0])
 		}
 		orig = with.Logger()
 	}
@@ -61,6 +65,28 @@ func (m MauZeroLog) GetParent() maulogger.Logger {
 	return nil
 }
 
+func (m MauZeroLog) Enabled(level maulogger.Level) bool {
+	zeroLevel := mauToZeroLevel(level)
+	return zeroLevel >= m.Logger.GetLevel() && zeroLevel >= zerolog.GlobalLevel()
+}
+
+func (m MauZeroLog) Begin(name string, fields ...interface{}) *maulogger.Operation {
+	return maulogger.BeginOperation(m, name, fields...)
+}
+
+func (m MauZeroLog) Recover(opts maulogger.RecoverOptions) {
+	if recovered := recover(); recovered != nil {
+		maulogger.HandlePanic(m, recovered, opts)
+	}
+}
+
+func (m MauZeroLog) Go(fn func()) {
+	go func() {
+		defer m.Recover(maulogger.RecoverOptions{})
+		fn()
+	}()
+}
+
 type nopWriteCloser struct {
 	io.Writer
 }
@@ -88,98 +114,117 @@ func mauToZeroLevel(level maulogger.Level) zerolog.Level {
 	}
 }
 
+// sendSprint formats the parts and sends the event. Lazy values are only evaluated if the event is enabled.
+func sendSprint(evt *zerolog.Event, parts []interface{}) {
+	if evt.Enabled() {
+		evt.Msg(fmt.Sprint(maulogger.ResolveLazy(parts)...))
+	}
+}
+
+func sendSprintln(evt *zerolog.Event, parts []interface{}) {
+	if evt.Enabled() {
+		evt.Msg(strings.TrimSuffix(fmt.Sprintln(maulogger.ResolveLazy(parts)...), "\n"))
+	}
+}
+
+func sendSprintf(evt *zerolog.Event, message string, args []interface{}) {
+	if evt.Enabled() {
+		evt.Msg(fmt.Sprintf(message, maulogger.ResolveLazy(args)...))
+	}
+}
+
 func (m MauZeroLog) Log(level maulogger.Level, parts ...interface{}) {
-	m.Logger.WithLevel(mauToZeroLevel(level)).Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.WithLevel(mauToZeroLevel(level)), parts)
 }
 
 func (m MauZeroLog) Logln(level maulogger.Level, parts ...interface{}) {
-	m.Logger.WithLevel(mauToZeroLevel(level)).Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.WithLevel(mauToZeroLevel(level)), parts)
 }
 
 func (m MauZeroLog) Logf(level maulogger.Level, message string, args ...interface{}) {
-	m.Logger.WithLevel(mauToZeroLevel(level)).Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.WithLevel(mauToZeroLevel(level)), message, args)
 }
 
 func (m MauZeroLog) Logfln(level maulogger.Level, message string, args ...interface{}) {
-	m.Logger.WithLevel(mauToZeroLevel(level)).Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.WithLevel(mauToZeroLevel(level)), message, args)
 }
 
 func (m MauZeroLog) Debug(parts ...interface{}) {
-	m.Logger.Debug().Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.Debug(), parts)
 }
 
 func (m MauZeroLog) Debugln(parts ...interface{}) {
-	m.Logger.Debug().Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.Debug(), parts)
 }
 
 func (m MauZeroLog) Debugf(message string, args ...interface{}) {
-	m.Logger.Debug().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Debug(), message, args)
 }
 
 func (m MauZeroLog) Debugfln(message string, args ...interface{}) {
-	m.Logger.Debug().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Debug(), message, args)
 }
 
 func (m MauZeroLog) Info(parts ...interface{}) {
-	m.Logger.Info().Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.Info(), parts)
 }
 
 func (m MauZeroLog) Infoln(parts ...interface{}) {
-	m.Logger.Info().Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.Info(), parts)
 }
 
 func (m MauZeroLog) Infof(message string, args ...interface{}) {
-	m.Logger.Info().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Info(), message, args)
 }
 
 func (m MauZeroLog) Infofln(message string, args ...interface{}) {
-	m.Logger.Info().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Info(), message, args)
 }
 
 func (m MauZeroLog) Warn(parts ...interface{}) {
-	m.Logger.Warn().Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.Warn(), parts)
 }
 
 func (m MauZeroLog) Warnln(parts ...interface{}) {
-	m.Logger.Warn().Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.Warn(), parts)
 }
 
 func (m MauZeroLog) Warnf(message string, args ...interface{}) {
-	m.Logger.Warn().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Warn(), message, args)
 }
 
 func (m MauZeroLog) Warnfln(message string, args ...interface{}) {
-	m.Logger.Warn().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Warn(), message, args)
 }
 
 func (m MauZeroLog) Error(parts ...interface{}) {
-	m.Logger.Error().Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.Error(), parts)
 }
 
 func (m MauZeroLog) Errorln(parts ...interface{}) {
-	m.Logger.Error().Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.Error(), parts)
 }
 
 func (m MauZeroLog) Errorf(message string, args ...interface{}) {
-	m.Logger.Error().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Error(), message, args)
 }
 
 func (m MauZeroLog) Errorfln(message string, args ...interface{}) {
-	m.Logger.Error().Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.Error(), message, args)
 }
 
 func (m MauZeroLog) Fatal(parts ...interface{}) {
-	m.Logger.WithLevel(zerolog.FatalLevel).Msg(fmt.Sprint(parts...))
+	sendSprint(m.Logger.WithLevel(zerolog.FatalLevel), parts)
 }
 
 func (m MauZeroLog) Fatalln(parts ...interface{}) {
-	m.Logger.WithLevel(zerolog.FatalLevel).Msg(strings.TrimSuffix(fmt.Sprintln(parts...), "\n"))
+	sendSprintln(m.Logger.WithLevel(zerolog.FatalLevel), parts)
 }
 
 func (m MauZeroLog) Fatalf(message string, args ...interface{}) {
-	m.Logger.WithLevel(zerolog.FatalLevel).Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.WithLevel(zerolog.FatalLevel), message, args)
 }
 
 func (m MauZeroLog) Fatalfln(message string, args ...interface{}) {
-	m.Logger.WithLevel(zerolog.FatalLevel).Msg(fmt.Sprintf(message, args...))
+	sendSprintf(m.Logger.WithLevel(zerolog.FatalLevel), message, args)
 }
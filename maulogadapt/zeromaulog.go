@@ -8,6 +8,7 @@ package maulogadapt
 
 import (
 	"bytes"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/tidwall/gjson"
@@ -52,6 +53,7 @@ func (z *ZeroMauLog) WriteLevel(level zerolog.Level, p []byte) (n int, err error
 	}
 	p = bytes.TrimSuffix(p, []byte{'\n'})
 	msg := gjson.GetBytes(p, zerolog.MessageFieldName).Str
+	ts := parseZeroTimestamp(gjson.GetBytes(p, zerolog.TimestampFieldName))
 
 	p, err = sjson.DeleteBytes(p, zerolog.MessageFieldName)
 	if err != nil {
@@ -68,6 +70,33 @@ func (z *ZeroMauLog) WriteLevel(level zerolog.Level, p []byte) (n int, err error
 	if len(p) > 2 {
 		msg += " " + string(p)
 	}
-	z.Log(mauLevel, msg)
+	if timedLog, ok := z.Logger.(maulogger.TimedLogger); ok && !ts.IsZero() {
+		timedLog.LogAt(ts, mauLevel, msg)
+	} else {
+		z.Log(mauLevel, msg)
+	}
 	return len(p), nil
 }
+
+// parseZeroTimestamp parses a timestamp written by zerolog with the current zerolog.TimeFieldFormat.
+// Zero is returned if the field is missing or can't be parsed.
+func parseZeroTimestamp(field gjson.Result) time.Time {
+	switch field.Type {
+	case gjson.Number:
+		switch zerolog.TimeFieldFormat {
+		case zerolog.TimeFormatUnix:
+			return time.Unix(field.Int(), 0)
+		case zerolog.TimeFormatUnixMs:
+			return time.UnixMilli(field.Int())
+		case zerolog.TimeFormatUnixMicro:
+			return time.UnixMicro(field.Int())
+		case zerolog.TimeFormatUnixNano:
+			return time.Unix(0, field.Int())
+		}
+	case gjson.String:
+		if ts, err := time.Parse(zerolog.TimeFieldFormat, field.Str); err == nil {
+			return ts
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogotel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+// Metadata keys used to carry span information from WithContext to the Sink.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+// WithContext returns a logger that attaches the active span in the given context to all entries.
+// If the context doesn't have a valid span, the logger is returned as-is.
+//
+// Metadata previously attached with Subm is kept if the logger has a Metadata method like
+// maulogger.Sublogger and the loggers wrapping it, and the span fields are added on top.
+func WithContext(ctx context.Context, log maulogger.Logger) maulogger.Logger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return log
+	}
+	var existing map[string]interface{}
+	if withMetadata, ok := log.(interface{ Metadata() map[string]interface{} }); ok {
+		existing = withMetadata.Metadata()
+	}
+	metadata := make(map[string]interface{}, len(existing)+3)
+	for key, value := range existing {
+		metadata[key] = value
+	}
+	metadata[TraceIDKey] = spanCtx.TraceID().String()
+	metadata[SpanIDKey] = spanCtx.SpanID().String()
+	metadata[TraceFlagsKey] = spanCtx.TraceFlags().String()
+	return log.Subm("", metadata)
+}
+
+// ErrQueueFull is returned by Sink inside a maulogger.DroppedError when too many records are waiting to be exported.
+var ErrQueueFull = errors.New("log record queue is full")
+
+// Sink is a maulogger.Sink that converts log lines into OpenTelemetry log records.
+//
+// Records are collected into batches and exported from a background goroutine, so a slow exporter
+// doesn't block logging. The exported fields can be changed after NewSink, but only before the first
+// WriteLine call.
+type Sink struct {
+	Exporter Exporter
+	// Timeout is the context timeout for each Export and the Shutdown call. Zero means no timeout.
+	Timeout time.Duration
+	// MaxBatchSize is the maximum number of records in a single Export call.
+	MaxBatchSize int
+	// FlushInterval is the maximum time a record waits before being exported. Zero or less means the default of 1 second.
+	FlushInterval time.Duration
+	// MaxQueueSize is the number of records that can wait to be exported before new ones are dropped.
+	MaxQueueSize int
+
+	// OnError is called from the background goroutine when a batch can't be exported.
+	// By default, errors are written to stderr.
+	OnError func(err error)
+
+	queue     chan Record
+	flush     chan chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	lock      sync.Mutex
+	closed    bool
+}
+
+var (
+	_ maulogger.Sink        = (*Sink)(nil)
+	_ maulogger.SinkFlusher = (*Sink)(nil)
+)
+
+const defaultFlushInterval = 1 * time.Second
+
+// NewSink creates a sink that sends log records to the given exporter.
+func NewSink(exporter Exporter) *Sink {
+	return &Sink{
+		Exporter:      exporter,
+		Timeout:       10 * time.Second,
+		MaxBatchSize:  512,
+		FlushInterval: defaultFlushInterval,
+		MaxQueueSize:  2048,
+
+		flush: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+type jsonLine struct {
+	Time     time.Time              `json:"time"`
+	Level    string                 `json:"level"`
+	Module   string                 `json:"module"`
+	Message  string                 `json:"message"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// ToRecord converts a JSON-encoded maulogger line into an OpenTelemetry log record.
+func ToRecord(level maulogger.Level, data []byte) (Record, error) {
+	var line jsonLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return Record{}, err
+	}
+	record := Record{
+		Timestamp:         line.Time,
+		ObservedTimestamp: time.Now(),
+		SeverityNumber:    LevelToSeverity(level),
+		SeverityText:      line.Level,
+		Body:              line.Message,
+		Attributes:        make(map[string]interface{}, len(line.Metadata)+1),
+	}
+	for key, value := range line.Metadata {
+		record.Attributes[key] = value
+	}
+	if len(line.Module) > 0 {
+		record.Attributes["module"] = line.Module
+	}
+	if traceID, ok := record.Attributes[TraceIDKey].(string); ok {
+		if parsed, err := trace.TraceIDFromHex(traceID); err == nil {
+			record.TraceID = parsed
+			delete(record.Attributes, TraceIDKey)
+		}
+	}
+	if spanID, ok := record.Attributes[SpanIDKey].(string); ok {
+		if parsed, err := trace.SpanIDFromHex(spanID); err == nil {
+			record.SpanID = parsed
+			delete(record.Attributes, SpanIDKey)
+		}
+	}
+	if flags, ok := record.Attributes[TraceFlagsKey].(string); ok {
+		if flags == trace.FlagsSampled.String() {
+			record.TraceFlags = trace.FlagsSampled
+		}
+		delete(record.Attributes, TraceFlagsKey)
+	}
+	return record, nil
+}
+
+func (sink *Sink) start() {
+	queueSize := sink.MaxQueueSize
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	sink.queue = make(chan Record, queueSize)
+	go sink.loop()
+}
+
+// WriteLine converts the line into a log record and queues it to be exported.
+func (sink *Sink) WriteLine(level maulogger.Level, data []byte) error {
+	sink.startOnce.Do(sink.start)
+	record, err := ToRecord(level, data)
+	if err != nil {
+		return err
+	}
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return maulogger.ErrSinkClosed
+	}
+	select {
+	case sink.queue <- record:
+		return nil
+	default:
+		return maulogger.DroppedError{Count: 1, Reason: ErrQueueFull}
+	}
+}
+
+func (sink *Sink) loop() {
+	defer close(sink.done)
+	flushInterval := sink.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	var batch []Record
+	for {
+		select {
+		case record := <-sink.queue:
+			batch = append(batch, record)
+			if len(batch) >= sink.MaxBatchSize {
+				sink.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			sink.export(batch)
+			batch = nil
+		case flushed := <-sink.flush:
+			sink.export(sink.drainQueue(batch))
+			batch = nil
+			close(flushed)
+		case <-sink.stop:
+			sink.export(sink.drainQueue(batch))
+			return
+		}
+	}
+}
+
+// drainQueue adds all queued records to the batch, exporting it whenever it fills up.
+func (sink *Sink) drainQueue(batch []Record) []Record {
+	for {
+		select {
+		case record := <-sink.queue:
+			batch = append(batch, record)
+			if len(batch) >= sink.MaxBatchSize {
+				sink.export(batch)
+				batch = nil
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+func (sink *Sink) context() (context.Context, context.CancelFunc) {
+	if sink.Timeout > 0 {
+		return context.WithTimeout(context.Background(), sink.Timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (sink *Sink) export(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := sink.context()
+	defer cancel()
+	if err := sink.Exporter.Export(ctx, batch); err != nil {
+		err = fmt.Errorf("dropping batch of %d log records: %w", len(batch), err)
+		if sink.OnError != nil {
+			sink.OnError(err)
+		} else {
+			_, _ = fmt.Fprintln(os.Stderr, "Failed to export logs to OpenTelemetry:", err)
+		}
+	}
+}
+
+// Flush exports all queued records and waits until the export is done.
+func (sink *Sink) Flush() {
+	sink.startOnce.Do(sink.start)
+	flushed := make(chan struct{})
+	select {
+	case sink.flush <- flushed:
+		<-flushed
+	case <-sink.done:
+	}
+}
+
+// Close exports all queued records, stops the background goroutine and shuts down the exporter.
+func (sink *Sink) Close() error {
+	sink.lock.Lock()
+	if sink.closed {
+		sink.lock.Unlock()
+		return nil
+	}
+	sink.closed = true
+	sink.lock.Unlock()
+	sink.startOnce.Do(func() { close(sink.done) })
+	close(sink.stop)
+	<-sink.done
+	ctx, cancel := sink.context()
+	defer cancel()
+	return sink.Exporter.Shutdown(ctx)
+}
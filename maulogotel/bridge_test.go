@@ -0,0 +1,95 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogotel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+func newTestLogger() (*maulogger.BasicLogger, *MemoryExporter, *Sink) {
+	log := maulogger.Createm(nil).(*maulogger.BasicLogger)
+	log.PrintLevel = maulogger.LevelFatal.Severity + 1
+	exporter := &MemoryExporter{}
+	sink := NewSink(exporter)
+	log.AddSink(sink)
+	return log, exporter, sink
+}
+
+func TestWithContextKeepsMetadata(t *testing.T) {
+	log, exporter, sink := newTestLogger()
+	defer log.Close()
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{4, 5, 6},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	sub := log.Sub("bridge").Subm("", map[string]interface{}{"room_id": "!meow"})
+	WithContext(ctx, sub).Infoln("Hello")
+	sink.Flush()
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	} else if records[0].TraceID != spanCtx.TraceID() || records[0].SpanID != spanCtx.SpanID() {
+		t.Errorf("Span not attached to record: %+v", records[0])
+	} else if records[0].Attributes["room_id"] != "!meow" {
+		t.Errorf("Sublogger metadata lost: %v", records[0].Attributes)
+	} else if records[0].Attributes["module"] != "bridge" {
+		t.Errorf("Unexpected module attribute %v", records[0].Attributes["module"])
+	}
+}
+
+func TestWithContextKeepsFingersCrossedMetadata(t *testing.T) {
+	log, exporter, sink := newTestLogger()
+	defer log.Close()
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+
+	scope := log.Sub("bridge").Subm("", map[string]interface{}{"room_id": "!meow"}).(*maulogger.Sublogger).FingersCrossed(maulogger.LevelWarn)
+	WithContext(ctx, scope).Warnln("Hello")
+	sink.Flush()
+
+	records := exporter.Records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	} else if records[0].Attributes["room_id"] != "!meow" {
+		t.Errorf("Scope metadata lost: %v", records[0].Attributes)
+	} else if records[0].TraceID != spanCtx.TraceID() {
+		t.Errorf("Span not attached to record: %+v", records[0])
+	}
+}
+
+func TestSinkBatches(t *testing.T) {
+	log, exporter, sink := newTestLogger()
+	sink.MaxBatchSize = 10
+	for i := 0; i < 25; i++ {
+		log.Infofln("Line %d", i)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records := exporter.Records()
+	if len(records) != 25 {
+		t.Fatalf("Expected 25 records, got %d", len(records))
+	}
+	for i, record := range records {
+		if expected := fmt.Sprintf("Line %d", i); record.Body != expected {
+			t.Errorf("Expected record %d to be %q, got %q", i, expected, record.Body)
+		}
+	}
+}
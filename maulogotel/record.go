@@ -0,0 +1,102 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogotel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+// SeverityNumber is the OpenTelemetry log record severity number.
+type SeverityNumber int32
+
+// Base severity numbers from the OpenTelemetry log data model.
+const (
+	SeverityUndefined SeverityNumber = 0
+	SeverityTrace     SeverityNumber = 1
+	SeverityDebug     SeverityNumber = 5
+	SeverityInfo      SeverityNumber = 9
+	SeverityWarn      SeverityNumber = 13
+	SeverityError     SeverityNumber = 17
+	SeverityFatal     SeverityNumber = 21
+)
+
+// LevelToSeverity maps a maulogger level to the closest OpenTelemetry severity number.
+func LevelToSeverity(level maulogger.Level) SeverityNumber {
+	switch {
+	case level.Severity < maulogger.LevelInfo.Severity:
+		return SeverityDebug
+	case level.Severity < maulogger.LevelWarn.Severity:
+		return SeverityInfo
+	case level.Severity < maulogger.LevelError.Severity:
+		return SeverityWarn
+	case level.Severity < maulogger.LevelFatal.Severity:
+		return SeverityError
+	default:
+		return SeverityFatal
+	}
+}
+
+// Record is an OpenTelemetry log record.
+type Record struct {
+	Timestamp         time.Time
+	ObservedTimestamp time.Time
+	SeverityNumber    SeverityNumber
+	SeverityText      string
+	Body              string
+	Attributes        map[string]interface{}
+
+	TraceID    trace.TraceID
+	SpanID     trace.SpanID
+	TraceFlags trace.TraceFlags
+}
+
+// Exporter sends log records to an OpenTelemetry backend.
+//
+// Applications usually implement this with an adapter around their OTLP exporter of choice.
+type Exporter interface {
+	Export(ctx context.Context, records []Record) error
+	Shutdown(ctx context.Context) error
+}
+
+// MemoryExporter is an Exporter that stores all records in memory. It's mostly useful for tests.
+type MemoryExporter struct {
+	records []Record
+	lock    sync.Mutex
+}
+
+var _ Exporter = (*MemoryExporter)(nil)
+
+func (exp *MemoryExporter) Export(_ context.Context, records []Record) error {
+	exp.lock.Lock()
+	exp.records = append(exp.records, records...)
+	exp.lock.Unlock()
+	return nil
+}
+
+func (exp *MemoryExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// Records returns a copy of all records exported so far.
+func (exp *MemoryExporter) Records() []Record {
+	exp.lock.Lock()
+	defer exp.lock.Unlock()
+	return append([]Record(nil), exp.records...)
+}
+
+// Reset removes all stored records.
+func (exp *MemoryExporter) Reset() {
+	exp.lock.Lock()
+	exp.records = nil
+	exp.lock.Unlock()
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogread
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+// ErrUnsupportedFileFormat is returned by RotatedFiles if the file name format can't be reversed.
+var ErrUnsupportedFileFormat = errors.New("unsupported log file name format")
+
+const (
+	dateMarker  = "\x00date\x00"
+	indexMarker = 987654321
+)
+
+// fileFormatRegex builds a regex that matches file names produced by the given format.
+func fileFormatRegex(fileFormat maulogger.LoggerFileFormat) (dir string, pattern *regexp.Regexp, dateGroup, indexGroup int, err error) {
+	sample := fileFormat(dateMarker, indexMarker)
+	dir, name := filepath.Split(sample)
+	indexStr := strconv.Itoa(indexMarker)
+	if strings.Contains(dir, dateMarker) || strings.Contains(dir, indexStr) || !strings.Contains(name, dateMarker) {
+		return "", nil, 0, 0, ErrUnsupportedFileFormat
+	}
+	var regex strings.Builder
+	regex.WriteByte('^')
+	group := 0
+	for len(name) > 0 {
+		dateIndex := strings.Index(name, dateMarker)
+		indexIndex := strings.Index(name, indexStr)
+		next, marker := dateIndex, dateMarker
+		if indexIndex >= 0 && (dateIndex < 0 || indexIndex < dateIndex) {
+			next, marker = indexIndex, indexStr
+		}
+		if next < 0 {
+			regex.WriteString(regexp.QuoteMeta(name))
+			break
+		}
+		regex.WriteString(regexp.QuoteMeta(name[:next]))
+		group++
+		if marker == dateMarker {
+			dateGroup = group
+			regex.WriteString("(.+?)")
+		} else {
+			indexGroup = group
+			regex.WriteString(`(\d+)`)
+		}
+		name = name[next+len(marker):]
+	}
+	regex.WriteByte('$')
+	pattern, err = regexp.Compile(regex.String())
+	return
+}
+
+type rotatedFile struct {
+	path  string
+	date  time.Time
+	index int
+}
+
+// RotatedFiles finds all log files created by BasicLogger.OpenFile with the given FileTimeFormat
+// and FileFormat, and returns their paths in chronological order.
+func RotatedFiles(fileTimeFormat string, fileFormat maulogger.LoggerFileFormat) ([]string, error) {
+	dir, pattern, dateGroup, indexGroup, err := fileFormatRegex(fileFormat)
+	if err != nil {
+		return nil, err
+	}
+	listDir := dir
+	if len(listDir) == 0 {
+		listDir = "."
+	}
+	dirEntries, err := os.ReadDir(listDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, dirEntry := range dirEntries {
+		match := pattern.FindStringSubmatch(dirEntry.Name())
+		if match == nil || dirEntry.IsDir() {
+			continue
+		}
+		file := rotatedFile{path: filepath.Join(dir, dirEntry.Name())}
+		if file.date, err = time.ParseInLocation(fileTimeFormat, match[dateGroup], time.Local); err != nil {
+			continue
+		}
+		if indexGroup > 0 {
+			file.index, _ = strconv.Atoi(match[indexGroup])
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].date.Equal(files[j].date) {
+			return files[i].date.Before(files[j].date)
+		}
+		return files[i].index < files[j].index
+	})
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+type multiFileReader struct {
+	paths   []string
+	current *os.File
+}
+
+func (mfr *multiFileReader) Read(p []byte) (int, error) {
+	for {
+		if mfr.current == nil {
+			if len(mfr.paths) == 0 {
+				return 0, io.EOF
+			}
+			file, err := os.Open(mfr.paths[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to open %s: %w", mfr.paths[0], err)
+			}
+			mfr.current = file
+			mfr.paths = mfr.paths[1:]
+		}
+		n, err := mfr.current.Read(p)
+		if err == io.EOF {
+			_ = mfr.current.Close()
+			mfr.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// OpenFiles creates a reader that reads the given files one after another.
+// Each file is only opened once the previous one has been read completely.
+func OpenFiles(paths ...string) *Reader {
+	return NewReader(&multiFileReader{paths: paths})
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogread
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// callerKey is the field name BasicLogger uses for the caller in text lines.
+const callerKey = "caller"
+
+// SplitMetadataText splits the trailing key=value pairs written by maulogger.AppendMetadataText off
+// a text mode message. It returns the message without the pairs and the parsed metadata, or the
+// message as-is and nil if it doesn't end with any pairs.
+//
+// Quoted keys and values are unquoted and JSON values are decoded, other values are kept as strings.
+// Text at the end of a message that happens to look like key=value pairs is also parsed as metadata.
+func SplitMetadataText(message string) (string, map[string]interface{}) {
+	for start := 1; start < len(message); start++ {
+		if message[start-1] != ' ' && message[start-1] != '\n' {
+			continue
+		} else if metadata, ok := parseMetadataFields(message[start:]); ok {
+			return strings.TrimRight(message[:start], " \n"), metadata
+		}
+	}
+	return message, nil
+}
+
+func parseMetadataFields(text string) (map[string]interface{}, bool) {
+	metadata := make(map[string]interface{})
+	for {
+		key, rest, ok := parseTextKey(text)
+		if !ok || !strings.HasPrefix(rest, "=") {
+			return nil, false
+		}
+		var value interface{}
+		value, rest, ok = parseTextValue(rest[1:])
+		if !ok {
+			return nil, false
+		}
+		metadata[key] = value
+		if len(rest) == 0 {
+			return metadata, true
+		} else if rest[0] != ' ' {
+			return nil, false
+		}
+		text = rest[1:]
+	}
+}
+
+func parseTextKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) {
+		return parseQuoted(text)
+	}
+	end := strings.IndexAny(text, " \n=\"")
+	if end <= 0 {
+		return "", "", false
+	}
+	return text[:end], text[end:], true
+}
+
+func parseTextValue(text string) (interface{}, string, bool) {
+	if len(text) == 0 {
+		return nil, "", false
+	}
+	switch text[0] {
+	case '"':
+		return parseQuoted(text)
+	case '{', '[':
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.UseNumber()
+		var value interface{}
+		if decoder.Decode(&value) != nil {
+			return nil, "", false
+		}
+		return value, text[decoder.InputOffset():], true
+	}
+	end := strings.IndexAny(text, " \n")
+	if end < 0 {
+		end = len(text)
+	}
+	value := text[:end]
+	if len(value) == 0 || strings.ContainsAny(value, `="`) {
+		return nil, "", false
+	}
+	return value, text[end:], true
+}
+
+func parseQuoted(text string) (string, string, bool) {
+	quoted, err := strconv.QuotedPrefix(text)
+	if err != nil {
+		return "", "", false
+	}
+	unquoted, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", false
+	}
+	return unquoted, text[len(quoted):], true
+}
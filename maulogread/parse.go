@@ -0,0 +1,216 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package maulogread parses log files written by maulogger's BasicLogger.
+package maulogread
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+// Format is the format of a log file.
+type Format int
+
+const (
+	// FormatAuto detects the format of each line separately.
+	FormatAuto Format = iota
+	// FormatText is the default text format of BasicLogger.
+	FormatText
+	// FormatJSON is the format used when BasicLogger.JSONFile is enabled.
+	FormatJSON
+)
+
+// ErrNotLogLine is returned when a line is neither a valid log line nor a continuation of the previous one.
+var ErrNotLogLine = errors.New("line is not a maulogger log line")
+
+// Entry is a single parsed log entry.
+type Entry struct {
+	Time    time.Time
+	Level   maulogger.Level
+	Module  string
+	Message string
+	// Metadata is parsed from the metadata object in JSON lines and from the trailing key=value
+	// pairs in text lines (see SplitMetadataText).
+	Metadata map[string]interface{}
+	// Caller is the file and line of the logging call, if the logger had ReportCaller enabled.
+	Caller string
+}
+
+// parseLevel returns the builtin level with the given name. Unknown names return a level with
+// the given name, no color and the severity of LevelInfo, so entries with custom levels aren't lost.
+func parseLevel(name string) maulogger.Level {
+	if level, err := maulogger.ParseLevel(name); err == nil && level.Name == name {
+		return level
+	}
+	return maulogger.Level{Name: name, Color: -1, Severity: maulogger.LevelInfo.Severity}
+}
+
+// Parser converts lines into entries. Text mode messages may span multiple lines, so entries are
+// only returned once the next entry starts or Flush is called.
+type Parser struct {
+	Format Format
+	// TimeFormat is the BasicLogger.TimeFormat used when writing the file.
+	TimeFormat string
+	// Location is the time zone used for parsing text mode timestamps. Defaults to time.Local.
+	Location *time.Location
+
+	pending     *Entry
+	pendingText bool
+}
+
+// NewParser creates a parser that auto-detects the format and uses the TimeFormat of maulogger.DefaultLogger.
+func NewParser() *Parser {
+	return &Parser{
+		Format:     FormatAuto,
+		TimeFormat: maulogger.DefaultLogger.TimeFormat,
+		Location:   time.Local,
+	}
+}
+
+type jsonLine struct {
+	Time     time.Time              `json:"time"`
+	Level    string                 `json:"level"`
+	Module   string                 `json:"module"`
+	Message  string                 `json:"message"`
+	Metadata map[string]interface{} `json:"metadata"`
+	Caller   string                 `json:"caller"`
+}
+
+func parseJSON(line string) (*Entry, error) {
+	var parsed jsonLine
+	if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+		return nil, err
+	}
+	return &Entry{
+		Time:     parsed.Time,
+		Level:    parseLevel(parsed.Level),
+		Module:   parsed.Module,
+		Message:  parsed.Message,
+		Metadata: parsed.Metadata,
+		Caller:   parsed.Caller,
+	}, nil
+}
+
+func (p *Parser) parseText(line string) (*Entry, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return nil, false
+	}
+	timeEnd := strings.Index(line, "] [")
+	if timeEnd < 0 {
+		return nil, false
+	}
+	location := p.Location
+	if location == nil {
+		location = time.Local
+	}
+	ts, err := time.ParseInLocation(p.TimeFormat, line[1:timeEnd], location)
+	if err != nil {
+		return nil, false
+	}
+	rest := line[timeEnd+3:]
+	var message string
+	headerEnd := strings.Index(rest, "] ")
+	if headerEnd >= 0 {
+		message = rest[headerEnd+2:]
+	} else if strings.HasSuffix(rest, "]") {
+		headerEnd = len(rest) - 1
+	} else {
+		return nil, false
+	}
+	header := rest[:headerEnd]
+	var module, level string
+	if slash := strings.LastIndexByte(header, '/'); slash >= 0 {
+		module, level = header[:slash], header[slash+1:]
+	} else {
+		level = header
+	}
+	return &Entry{
+		Time:    ts,
+		Level:   parseLevel(level),
+		Module:  module,
+		Message: message,
+	}, true
+}
+
+// finishText moves the trailing metadata and caller fields of a complete text entry out of the message.
+func finishText(entry *Entry) {
+	entry.Message, entry.Metadata = SplitMetadataText(entry.Message)
+	if caller, ok := entry.Metadata[callerKey].(string); ok {
+		entry.Caller = caller
+		delete(entry.Metadata, callerKey)
+	}
+}
+
+// ParseLine parses a single line without a trailing newline. If the line starts a new entry,
+// the previous pending entry is returned. Continuation lines are appended to the pending entry.
+func (p *Parser) ParseLine(line string) (*Entry, error) {
+	line = strings.TrimSuffix(line, "\r")
+	var entry *Entry
+	var isText bool
+	if p.Format != FormatText && strings.HasPrefix(line, "{") {
+		var err error
+		entry, err = parseJSON(line)
+		if err != nil && p.Format == FormatJSON {
+			return nil, err
+		}
+	} else if p.Format == FormatJSON {
+		if len(strings.TrimSpace(line)) == 0 {
+			return nil, nil
+		}
+		return nil, ErrNotLogLine
+	}
+	if entry == nil {
+		var ok bool
+		entry, ok = p.parseText(line)
+		isText = true
+		if !ok {
+			if p.pending == nil {
+				if len(strings.TrimSpace(line)) == 0 {
+					return nil, nil
+				}
+				return nil, ErrNotLogLine
+			}
+			p.pending.Message += "\n" + trimContinuation(line)
+			return nil, nil
+		} else if prev := p.pending; prev != nil && strings.HasPrefix(entry.Message, "| ") &&
+			entry.Time.Equal(prev.Time) && entry.Module == prev.Module && entry.Level.Name == prev.Level.Name {
+			// Continuation line with a repeated header (maulogger.NewlineHeader)
+			prev.Message += "\n" + entry.Message[2:]
+			return nil, nil
+		}
+	}
+	prev := p.Flush()
+	p.pending = entry
+	p.pendingText = isText
+	return prev, nil
+}
+
+// trimContinuation removes the prefix BasicLogger adds to continuation lines with
+// maulogger.NewlineIndent (a tab) or maulogger.NewlineGutter (aligned spaces and a `| ` gutter).
+func trimContinuation(line string) string {
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	} else if trimmed := strings.TrimLeft(line, " "); strings.HasPrefix(trimmed, "| ") && len(trimmed) < len(line) {
+		return trimmed[2:]
+	}
+	return line
+}
+
+// Flush returns the pending entry, if any.
+func (p *Parser) Flush() *Entry {
+	entry := p.pending
+	if entry != nil && p.pendingText {
+		finishText(entry)
+	}
+	p.pending = nil
+	p.pendingText = false
+	return entry
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogread
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"maunium.net/go/maulogger/v2"
+)
+
+func TestSplitMetadataText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		message  string
+		metadata map[string]interface{}
+	}{
+		{"NoMetadata", "Hello world", "Hello world", nil},
+		{"Bare", "Hello world room_id=!meow count=5", "Hello world", map[string]interface{}{"room_id": "!meow", "count": "5"}},
+		{"Quoted", `Hello user="a b" "odd key"=""`, "Hello", map[string]interface{}{"user": "a b", "odd key": ""}},
+		{"JSON", `Hello data={"x":1,"y":[true]}`, "Hello", map[string]interface{}{"data": map[string]interface{}{"x": json.Number("1"), "y": []interface{}{true}}}},
+		{"MetadataLine", "Multi\nline\n    key=value", "Multi\nline", map[string]interface{}{"key": "value"}},
+		{"EqualsInMessage", "a=b c", "a=b c", nil},
+		{"OnlyTrailingPairs", "Set x = 5 y=6", "Set x = 5", map[string]interface{}{"y": "6"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message, metadata := SplitMetadataText(test.input)
+			if message != test.message {
+				t.Errorf("Expected message %q, got %q", test.message, message)
+			} else if !reflect.DeepEqual(metadata, test.metadata) {
+				t.Errorf("Expected metadata %v, got %v", test.metadata, metadata)
+			}
+		})
+	}
+}
+
+func TestParseTextMetadata(t *testing.T) {
+	parser := NewParser()
+	parser.TimeFormat = "2006-01-02 15:04:05"
+	lines := []string{
+		"[2023-01-02 15:04:05] [bridge/WARN] Multi",
+		`	line room_id=!meow caller=bridge/sync.go:12`,
+		"[2023-01-02 15:04:06] [TRACE] Custom level",
+	}
+	var entries []*Entry
+	for _, line := range lines {
+		entry, err := parser.ParseLine(line)
+		if err != nil {
+			t.Fatal(err)
+		} else if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, parser.Flush())
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	first := entries[0]
+	if first.Message != "Multi\nline" {
+		t.Errorf("Unexpected message %q", first.Message)
+	} else if first.Metadata["room_id"] != "!meow" || len(first.Metadata) != 1 {
+		t.Errorf("Unexpected metadata %v", first.Metadata)
+	} else if first.Caller != "bridge/sync.go:12" {
+		t.Errorf("Unexpected caller %q", first.Caller)
+	} else if first.Level != maulogger.LevelWarn {
+		t.Errorf("Unexpected level %v", first.Level)
+	}
+	if second := entries[1]; second.Level.Name != "TRACE" || second.Level.Severity != maulogger.LevelInfo.Severity {
+		t.Errorf("Unexpected custom level %v", second.Level)
+	}
+}
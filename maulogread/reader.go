@@ -0,0 +1,125 @@
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogread
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Reader reads entries from a stream of log lines.
+type Reader struct {
+	*Parser
+	input *bufio.Reader
+	eof   bool
+}
+
+// NewReader creates a reader with the default parser settings. The Parser fields can be changed
+// before the first Next call.
+func NewReader(input io.Reader) *Reader {
+	return &Reader{
+		Parser: NewParser(),
+		input:  bufio.NewReader(input),
+	}
+}
+
+// Next returns the next entry. At the end of the stream, it returns io.EOF.
+func (r *Reader) Next() (*Entry, error) {
+	for !r.eof {
+		line, err := r.input.ReadString('\n')
+		if err == io.EOF {
+			r.eof = true
+			if len(line) == 0 {
+				break
+			}
+		} else if err != nil {
+			return nil, err
+		}
+		entry, err := r.ParseLine(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			return nil, err
+		} else if entry != nil {
+			return entry, nil
+		}
+	}
+	if entry := r.Flush(); entry != nil {
+		return entry, nil
+	}
+	return nil, io.EOF
+}
+
+// ReadAll reads all entries from the reader.
+func (r *Reader) ReadAll() ([]*Entry, error) {
+	var entries []*Entry
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Follow reads entries from the given file and keeps waiting for new lines until the context is canceled.
+// If the file is truncated, reading restarts from the beginning. Lines that aren't log lines are skipped.
+//
+// Text mode entries are passed to the handler when the next entry starts, or when no new data has
+// been written for one poll interval.
+func Follow(ctx context.Context, path string, parser *Parser, pollInterval time.Duration, handler func(*Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var offset int64
+	var partial string
+	input := bufio.NewReader(file)
+	for {
+		line, err := input.ReadString('\n')
+		offset += int64(len(line))
+		if err == io.EOF {
+			partial += line
+			if entry := parser.Flush(); entry != nil {
+				if err = handler(entry); err != nil {
+					return err
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pollInterval):
+			}
+			if stat, statErr := file.Stat(); statErr == nil && stat.Size() < offset {
+				if _, err = file.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+				offset = 0
+				partial = ""
+				input.Reset(file)
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		entry, err := parser.ParseLine(strings.TrimSuffix(partial+line, "\n"))
+		partial = ""
+		if err != nil && !errors.Is(err, ErrNotLogLine) {
+			return err
+		} else if entry != nil {
+			if err = handler(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Output names passed to MetricsCollector.WriteError.
+const (
+	OutputFile   = "file"
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputSink   = "sink"
+)
+
+// MetricsCollector receives events about logging volume and errors.
+//
+// The module passed to the collector is the top-level module, i.e. everything before the first slash.
+type MetricsCollector interface {
+	EntryLogged(level Level, module string)
+	EntryDropped(level Level, module string)
+	WriteError(output string, err error)
+	FileBytesWritten(n int)
+}
+
+func topLevelModule(module string) string {
+	if slash := strings.IndexByte(module, '/'); slash >= 0 {
+		return module[:slash]
+	}
+	return module
+}
+
+type levelModule struct {
+	level  string
+	module string
+}
+
+// Metrics is a MetricsCollector that keeps counters in memory and can serve them in the
+// Prometheus text exposition format.
+type Metrics struct {
+	entries          map[levelModule]uint64
+	dropped          map[levelModule]uint64
+	writeErrors      map[string]uint64
+	fileBytesWritten uint64
+	lock             sync.Mutex
+}
+
+var _ MetricsCollector = (*Metrics)(nil)
+var _ http.Handler = (*Metrics)(nil)
+
+// NewMetrics creates an empty set of logging metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		entries:     make(map[levelModule]uint64),
+		dropped:     make(map[levelModule]uint64),
+		writeErrors: make(map[string]uint64),
+	}
+}
+
+func (m *Metrics) EntryLogged(level Level, module string) {
+	m.lock.Lock()
+	m.entries[levelModule{level.Name, module}]++
+	m.lock.Unlock()
+}
+
+func (m *Metrics) EntryDropped(level Level, module string) {
+	m.lock.Lock()
+	m.dropped[levelModule{level.Name, module}]++
+	m.lock.Unlock()
+}
+
+func (m *Metrics) WriteError(output string, _ error) {
+	m.lock.Lock()
+	m.writeErrors[output]++
+	m.lock.Unlock()
+}
+
+func (m *Metrics) FileBytesWritten(n int) {
+	m.lock.Lock()
+	m.fileBytesWritten += uint64(n)
+	m.lock.Unlock()
+}
+
+// Entries returns the number of entries logged with the given level name and top-level module.
+func (m *Metrics) Entries(level, module string) uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.entries[levelModule{level, module}]
+}
+
+// Dropped returns the number of entries with the given level name and top-level module that were
+// dropped by hooks or sinks.
+func (m *Metrics) Dropped(level, module string) uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.dropped[levelModule{level, module}]
+}
+
+// WriteErrors returns the number of failed writes to the given output.
+func (m *Metrics) WriteErrors(output string) uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.writeErrors[output]
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLevelModuleCounter(w io.Writer, name, help string, values map[levelModule]uint64) {
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([]levelModule, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].level != keys[j].level {
+			return keys[i].level < keys[j].level
+		}
+		return keys[i].module < keys[j].module
+	})
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(w, "%s{level=\"%s\",module=\"%s\"} %d\n", name, labelEscaper.Replace(key.level), labelEscaper.Replace(key.module), values[key])
+	}
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteText(w io.Writer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	writeLevelModuleCounter(w, "maulogger_entries_total", "Number of log entries by level and top-level module.", m.entries)
+	writeLevelModuleCounter(w, "maulogger_dropped_entries_total", "Number of log entries dropped by hooks or sinks.", m.dropped)
+	_, _ = fmt.Fprint(w, "# HELP maulogger_write_errors_total Number of failed writes by output.\n# TYPE maulogger_write_errors_total counter\n")
+	outputs := make([]string, 0, len(m.writeErrors))
+	for output := range m.writeErrors {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+	for _, output := range outputs {
+		_, _ = fmt.Fprintf(w, "maulogger_write_errors_total{output=\"%s\"} %d\n", labelEscaper.Replace(output), m.writeErrors[output])
+	}
+	_, _ = fmt.Fprintf(w, "# HELP maulogger_file_bytes_written_total Number of bytes written to the log file.\n# TYPE maulogger_file_bytes_written_total counter\nmaulogger_file_bytes_written_total %d\n", m.fileBytesWritten)
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
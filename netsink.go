@@ -0,0 +1,534 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSinkClosed is returned when writing to a sink that has already been closed.
+var ErrSinkClosed = errors.New("log sink is closed")
+
+// ErrSpoolFull is returned by NetSink inside a DroppedError when the collector is unreachable and the spool is full.
+var ErrSpoolFull = errors.New("log spool is full")
+
+// ErrSinkQueueFull is returned by NetSink inside a DroppedError when the send queue is full and there's no spool to fall back to.
+var ErrSinkQueueFull = errors.New("log sink queue is full")
+
+// ErrSinkDisconnected is returned by NetSink inside a DroppedError when the collector is unreachable and there's no spool.
+var ErrSinkDisconnected = errors.New("log collector is not connected")
+
+// NetSink is a Sink that streams newline-delimited JSON log lines to a TCP or UDP collector.
+//
+// Lines are put on a bounded in-memory queue and sent from a background goroutine, so a slow or
+// unreachable collector never blocks logging. If the collector can't be reached or the queue is full,
+// lines are appended to an on-disk spool file and the sink reconnects in the background with
+// exponential backoff. Spooled lines are replayed in order before any new lines are sent.
+//
+// The exported fields can be changed after NewNetSink, but only before the first WriteLine call.
+type NetSink struct {
+	Network string
+	Address string
+
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	// QueueSize is the number of lines that can wait in memory to be sent before new lines are spooled.
+	QueueSize int
+	// SpoolMaxSize is the maximum size of the spool file in bytes. Zero means unlimited.
+	// Lines that were already queued when the connection broke are always kept, even if that exceeds the limit.
+	SpoolMaxSize int64
+
+	queue     chan []byte
+	wake      chan struct{}
+	flush     chan chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startOnce sync.Once
+
+	lock      sync.Mutex
+	spool     *os.File
+	spoolPath string
+	spoolSize int64
+	direct    bool
+	closed    bool
+
+	// spoolOffset is the number of bytes at the start of the spool that have already been sent.
+	// It's persisted next to the spool, so the spool is never rewritten while logging.
+	spoolOffset int64
+	// retry holds lines that were taken from the queue but couldn't be sent. They're older than
+	// everything in the spool, so they're sent first after reconnecting. Only used by the sender.
+	retry [][]byte
+}
+
+var _ Sink = (*NetSink)(nil)
+
+// NewNetSink creates a sink that sends log lines to the given address. The network must be one
+// that net.Dial supports, usually "tcp" or "udp".
+//
+// If spoolPath is empty, lines written while the collector is unreachable are dropped and WriteLine
+// returns a DroppedError wrapping ErrSinkDisconnected.
+func NewNetSink(network, address, spoolPath string) (*NetSink, error) {
+	sink := &NetSink{
+		Network:      network,
+		Address:      address,
+		DialTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		MinBackoff:   1 * time.Second,
+		MaxBackoff:   2 * time.Minute,
+		QueueSize:    1024,
+
+		wake:  make(chan struct{}, 1),
+		flush: make(chan chan struct{}),
+		done:  make(chan struct{}),
+	}
+	sink.ctx, sink.cancel = context.WithCancel(context.Background())
+	if len(spoolPath) > 0 {
+		spool, err := os.OpenFile(spoolPath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := spool.Stat()
+		if err != nil {
+			_ = spool.Close()
+			return nil, err
+		}
+		sink.spool = spool
+		sink.spoolPath = spoolPath
+		sink.spoolSize, err = trimUnterminatedTail(spool, stat.Size())
+		if err != nil {
+			_ = spool.Close()
+			return nil, err
+		}
+		sink.spoolOffset = loadSpoolOffset(spoolPath, sink.spoolSize)
+	}
+	return sink, nil
+}
+
+// trimUnterminatedTail removes a partial last line from the spool, e.g. after a crash in the middle of a write.
+func trimUnterminatedTail(spool *os.File, size int64) (int64, error) {
+	block := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := end - int64(len(block))
+		if start < 0 {
+			start = 0
+		}
+		n, err := spool.ReadAt(block[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if newline := bytes.LastIndexByte(block[:n], '\n'); newline >= 0 {
+			size = start + int64(newline) + 1
+			break
+		} else if start == 0 {
+			size = 0
+		}
+		end = start
+	}
+	if stat, err := spool.Stat(); err != nil {
+		return 0, err
+	} else if stat.Size() != size {
+		return size, spool.Truncate(size)
+	}
+	return size, nil
+}
+
+func spoolOffsetPath(spoolPath string) string {
+	return spoolPath + ".offset"
+}
+
+// loadSpoolOffset reads the persisted offset of the first unsent line in the spool.
+func loadSpoolOffset(spoolPath string, spoolSize int64) int64 {
+	data, err := os.ReadFile(spoolOffsetPath(spoolPath))
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || offset < 0 || offset > spoolSize {
+		return 0
+	}
+	return offset
+}
+
+func (sink *NetSink) saveSpoolOffset() {
+	if len(sink.spoolPath) > 0 {
+		_ = os.WriteFile(spoolOffsetPath(sink.spoolPath), []byte(strconv.FormatInt(sink.spoolOffset, 10)), 0600)
+	}
+}
+
+func (sink *NetSink) start() {
+	queueSize := sink.QueueSize
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	sink.queue = make(chan []byte, queueSize)
+	go sink.loop()
+}
+
+// WriteLine queues the line to be sent to the collector, or spools it if the collector is
+// currently unreachable or the queue is full. It never waits for network I/O.
+func (sink *NetSink) WriteLine(_ Level, data []byte) error {
+	sink.startOnce.Do(sink.start)
+	line := make([]byte, len(data)+1)
+	copy(line, data)
+	line[len(data)] = '\n'
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if sink.closed {
+		return ErrSinkClosed
+	} else if sink.direct {
+		select {
+		case sink.queue <- line:
+			return nil
+		default:
+		}
+		if sink.spool == nil {
+			return DroppedError{Count: 1, Reason: ErrSinkQueueFull}
+		}
+		// Spool this and all following lines until the sender has caught up, so that lines stay in order.
+		sink.direct = false
+		select {
+		case sink.wake <- struct{}{}:
+		default:
+		}
+	}
+	return sink.spoolLine(line)
+}
+
+func (sink *NetSink) send(conn net.Conn, line []byte) error {
+	if sink.WriteTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(sink.WriteTimeout))
+	}
+	_, err := conn.Write(line)
+	return err
+}
+
+// spoolLine appends the line to the spool. The caller must hold the lock.
+func (sink *NetSink) spoolLine(line []byte) error {
+	if sink.spool == nil {
+		return DroppedError{Count: 1, Reason: ErrSinkDisconnected}
+	} else if sink.SpoolMaxSize > 0 && sink.spoolSize+int64(len(line)) > sink.SpoolMaxSize {
+		return DroppedError{Count: 1, Reason: ErrSpoolFull}
+	}
+	return sink.appendSpool(line)
+}
+
+// appendSpool appends the line to the spool without checking SpoolMaxSize. The caller must hold the lock.
+func (sink *NetSink) appendSpool(line []byte) error {
+	n, err := sink.spool.Write(line)
+	if err != nil && n > 0 {
+		// Don't leave a partial line in the spool, the next line would be glued to it.
+		_ = sink.spool.Truncate(sink.spoolSize)
+	} else {
+		sink.spoolSize += int64(n)
+	}
+	return err
+}
+
+// requeue keeps the given line and everything left in the queue to be sent after reconnecting.
+// If everything in the spool has already been sent, the lines are moved to the spool, as lines
+// written after this are spooled behind them anyway.
+func (sink *NetSink) requeue(line []byte) {
+	if line != nil {
+		sink.retry = append(sink.retry, line)
+	}
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	sink.direct = false
+drain:
+	for {
+		select {
+		case queued := <-sink.queue:
+			sink.retry = append(sink.retry, queued)
+		default:
+			break drain
+		}
+	}
+	if sink.spool == nil || sink.spoolOffset < sink.spoolSize {
+		return
+	}
+	for i, retryLine := range sink.retry {
+		if sink.appendSpool(retryLine) != nil {
+			sink.retry = sink.retry[i:]
+			return
+		}
+	}
+	sink.retry = nil
+}
+
+// sendRetry sends the lines that failed on the previous connection.
+func (sink *NetSink) sendRetry(conn net.Conn) error {
+	for i, line := range sink.retry {
+		if err := sink.send(conn, line); err != nil {
+			sink.retry = sink.retry[i:]
+			return err
+		}
+	}
+	sink.retry = nil
+	return nil
+}
+
+const spoolReadChunkSize = 64 * 1024
+
+// replaySpool sends all unsent spooled lines to the connection. Lines spooled while replaying are
+// sent too, and once everything has been sent, the spool is emptied and new lines go through the
+// queue again. If sending fails partway, the offset of the first unsent line is saved.
+func (sink *NetSink) replaySpool(conn net.Conn) error {
+	chunk := make([]byte, spoolReadChunkSize)
+	for {
+		if err := sink.ctx.Err(); err != nil {
+			return err
+		}
+		sink.lock.Lock()
+		if sink.spoolOffset >= sink.spoolSize {
+			var err error
+			if sink.spool != nil && sink.spoolSize > 0 {
+				err = sink.spool.Truncate(0)
+			}
+			if err == nil {
+				sink.spoolSize = 0
+				sink.spoolOffset = 0
+				sink.direct = true
+			}
+			sink.lock.Unlock()
+			if err == nil {
+				sink.saveSpoolOffset()
+			}
+			return err
+		}
+		remaining := sink.spoolSize - sink.spoolOffset
+		readSize := remaining
+		if readSize > int64(len(chunk)) {
+			readSize = int64(len(chunk))
+		}
+		n, err := sink.spool.ReadAt(chunk[:readSize], sink.spoolOffset)
+		sink.lock.Unlock()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		data := chunk[:n]
+		end := bytes.LastIndexByte(data, '\n') + 1
+		if end == 0 {
+			if int64(n) >= remaining {
+				// Partial lines are removed when writing fails, so this shouldn't happen, but make sure it can't get stuck.
+				sink.spoolOffset += int64(n)
+				continue
+			}
+			// The line doesn't fit in the chunk, read it again with a bigger buffer.
+			chunk = make([]byte, len(chunk)*2)
+			continue
+		}
+		for start := 0; start < end; {
+			lineEnd := start + bytes.IndexByte(data[start:end], '\n') + 1
+			if err = sink.send(conn, data[start:lineEnd]); err != nil {
+				sink.spoolOffset += int64(start)
+				sink.saveSpoolOffset()
+				return err
+			}
+			start = lineEnd
+		}
+		sink.spoolOffset += int64(end)
+		sink.saveSpoolOffset()
+	}
+}
+
+// loop connects to the collector and sends lines until the sink is closed, reconnecting with
+// exponential backoff whenever the connection fails.
+func (sink *NetSink) loop() {
+	defer close(sink.done)
+	minBackoff := sink.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	backoff := minBackoff
+	dialer := net.Dialer{Timeout: sink.DialTimeout}
+	for {
+		conn, err := dialer.DialContext(sink.ctx, sink.Network, sink.Address)
+		if err == nil {
+			backoff = minBackoff
+			sink.run(conn)
+			_ = conn.Close()
+		}
+		if !sink.waitBackoff(backoff) {
+			sink.requeue(nil)
+			return
+		}
+		backoff *= 2
+		if backoff > sink.MaxBackoff {
+			backoff = sink.MaxBackoff
+		}
+	}
+}
+
+// waitBackoff waits before reconnecting and handles flushes in the meantime by moving queued lines
+// to the spool. It returns false if the sink was closed.
+func (sink *NetSink) waitBackoff(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case flushed := <-sink.flush:
+			sink.requeue(nil)
+			sink.syncSpool()
+			close(flushed)
+		case <-sink.ctx.Done():
+			return false
+		}
+	}
+}
+
+func (sink *NetSink) syncSpool() {
+	sink.lock.Lock()
+	if sink.spool != nil {
+		_ = sink.spool.Sync()
+	}
+	sink.lock.Unlock()
+}
+
+// run sends spooled and queued lines to the connection until it fails or the sink is closed.
+func (sink *NetSink) run(conn net.Conn) {
+	if sink.sendRetry(conn) != nil {
+		return
+	}
+	for {
+		if sink.replaySpool(conn) != nil {
+			return
+		}
+	sendQueued:
+		for {
+			select {
+			case line := <-sink.queue:
+				if err := sink.send(conn, line); err != nil {
+					sink.requeue(line)
+					return
+				}
+			case <-sink.wake:
+				// New lines are being spooled, send the older ones left in the queue before replaying them.
+				if !sink.drainQueue(conn) {
+					return
+				}
+				break sendQueued
+			case flushed := <-sink.flush:
+				ok := sink.drainQueue(conn)
+				if !ok {
+					sink.syncSpool()
+				}
+				close(flushed)
+				if !ok {
+					return
+				}
+			case <-sink.ctx.Done():
+				sink.drainQueue(conn)
+				return
+			}
+		}
+	}
+}
+
+// drainQueue sends the lines currently in the queue without waiting for new ones.
+func (sink *NetSink) drainQueue(conn net.Conn) bool {
+	for {
+		select {
+		case line := <-sink.queue:
+			if err := sink.send(conn, line); err != nil {
+				sink.requeue(line)
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+// Flush waits until the lines in the queue have been sent to the collector. If the collector isn't
+// connected, the queued lines are moved to the spool instead. Flush may block for up to DialTimeout
+// while the sink is connecting.
+func (sink *NetSink) Flush() {
+	sink.startOnce.Do(sink.start)
+	flushed := make(chan struct{})
+	select {
+	case sink.flush <- flushed:
+		<-flushed
+	case <-sink.done:
+	}
+}
+
+// Close stops the background sender and closes the spool file. Lines still in the queue are sent
+// if the collector is connected, otherwise they're spooled. Lines remaining in the spool are kept
+// on disk and will be replayed by the next NetSink that uses the same spool path.
+func (sink *NetSink) Close() error {
+	sink.lock.Lock()
+	if sink.closed {
+		sink.lock.Unlock()
+		return nil
+	}
+	sink.closed = true
+	sink.lock.Unlock()
+	sink.startOnce.Do(func() { close(sink.done) })
+	sink.cancel()
+	<-sink.done
+
+	// The sender has stopped and WriteLine doesn't touch the spool after closing, so the lock isn't needed anymore.
+	if sink.spool == nil {
+		return nil
+	} else if len(sink.retry) > 0 {
+		return sink.rewriteSpool()
+	}
+	sink.saveSpoolOffset()
+	return sink.spool.Close()
+}
+
+// rewriteSpool replaces the spool with the unsent lines that were taken from the queue followed by
+// the unsent part of the old spool. It's only used when closing, so it doesn't block logging.
+func (sink *NetSink) rewriteSpool() error {
+	tempPath := sink.spoolPath + ".tmp"
+	temp, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		_ = sink.spool.Close()
+		return err
+	}
+	for _, line := range sink.retry {
+		if _, err = temp.Write(line); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		_, err = io.Copy(temp, io.NewSectionReader(sink.spool, sink.spoolOffset, sink.spoolSize-sink.spoolOffset))
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := sink.spool.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err = os.Rename(tempPath, sink.spoolPath); err != nil {
+		return err
+	}
+	sink.retry = nil
+	sink.spoolOffset = 0
+	sink.saveSpoolOffset()
+	return nil
+}
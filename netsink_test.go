@@ -0,0 +1,309 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNetSinkReplaysSpoolInOrder(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	if err = os.WriteFile(spoolPath, []byte("{\"n\":0}\n{\"n\":1}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewNetSink("tcp", listener.Addr().String(), spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	for i := 2; i < 100; i++ {
+		if err = sink.WriteLine(LevelInfo, []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(conn)
+	for i := 0; i < 100; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Expected line %d, got error %v", i, scanner.Err())
+		} else if expected := fmt.Sprintf(`{"n":%d}`, i); scanner.Text() != expected {
+			t.Fatalf("Expected line %d to be %s, got %s", i, expected, scanner.Text())
+		}
+	}
+}
+
+func TestNetSinkDoesNotBlockOnStalledCollector(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	stalled := make(chan struct{})
+	defer close(stalled)
+	go func() {
+		// Accept the connection but never read from it.
+		conn, err := listener.Accept()
+		if err == nil {
+			<-stalled
+			_ = conn.Close()
+		}
+	}()
+
+	sink, err := NewNetSink("tcp", listener.Addr().String(), filepath.Join(t.TempDir(), "spool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	sink.QueueSize = 16
+	sink.WriteTimeout = time.Second
+	line := make([]byte, 64*1024)
+	for i := range line {
+		line[i] = 'a'
+	}
+	start := time.Now()
+	for i := 0; i < 200; i++ {
+		if err = sink.WriteLine(LevelInfo, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Writing to a stalled collector took %s", elapsed)
+	}
+	sink.lock.Lock()
+	spoolSize := sink.spoolSize
+	sink.lock.Unlock()
+	if spoolSize == 0 {
+		t.Error("Expected lines to be spooled while the collector is stalled")
+	}
+}
+
+func TestNetSinkDropsUnterminatedSpoolTail(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	if err = os.WriteFile(spoolPath, []byte("{\"n\":0}\n{\"n\":"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewNetSink("tcp", listener.Addr().String(), spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err = sink.WriteLine(LevelInfo, []byte(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(conn)
+	for i := 0; i < 2; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Expected line %d, got error %v", i, scanner.Err())
+		} else if expected := fmt.Sprintf(`{"n":%d}`, i); scanner.Text() != expected {
+			t.Fatalf("Expected line %d to be %s, got %s", i, expected, scanner.Text())
+		}
+	}
+}
+
+func TestNetSinkResumesFromSavedOffset(t *testing.T) {
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	if err := os.WriteFile(spoolPath, []byte("{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n"), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(spoolPath+".offset", []byte("16"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	sink, err := NewNetSink("tcp", listener.Addr().String(), spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err = sink.WriteLine(LevelInfo, []byte(`{"n":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(conn)
+	for i := 2; i < 4; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Expected line %d, got error %v", i, scanner.Err())
+		} else if expected := fmt.Sprintf(`{"n":%d}`, i); scanner.Text() != expected {
+			t.Fatalf("Expected line %d to be %s, got %s", i, expected, scanner.Text())
+		}
+	}
+}
+
+func TestNetSinkKeepsQueuedLinesOnClose(t *testing.T) {
+	// Nothing listens on the address, so the lines stay queued or spooled.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	sink, err := NewNetSink("tcp", address, spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = sink.WriteLine(LevelInfo, []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(data) != "{\"n\":0}\n{\"n\":1}\n{\"n\":2}\n" {
+		t.Errorf("Unexpected spool contents %q", data)
+	}
+}
+
+func TestNetSinkCountsDroppedLinesWithoutSpool(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	sink, err := NewNetSink("tcp", address, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.WriteLine(LevelInfo, []byte(`{"n":1}`))
+	if !errors.Is(err, ErrSinkDisconnected) {
+		t.Fatalf("Expected ErrSinkDisconnected, got %v", err)
+	}
+
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	log.PrintLevel = LevelFatal.Severity + 1
+	metrics := NewMetrics()
+	log.SetMetrics(metrics)
+	log.AddSink(sink)
+	defer log.Close()
+	log.Sub("Net").Infoln("meow")
+	if dropped := metrics.Dropped(LevelInfo.Name, "Net"); dropped != 1 {
+		t.Errorf("Expected 1 dropped entry, got %d", dropped)
+	} else if writeErrors := metrics.WriteErrors(OutputSink); writeErrors != 0 {
+		t.Errorf("Expected drops not to be counted as write errors, got %d", writeErrors)
+	}
+}
+
+func TestNetSinkFlush(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	sink, err := NewNetSink("tcp", listener.Addr().String(), filepath.Join(t.TempDir(), "spool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err = sink.WriteLine(LevelInfo, []byte(`{"n":0}`)); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(conn)
+
+	// Wait until the sink has connected and switched to the queue
+	for {
+		sink.lock.Lock()
+		direct := sink.direct
+		sink.lock.Unlock()
+		if direct {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i <= 100; i++ {
+		if err = sink.WriteLine(LevelInfo, []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.Flush()
+	if queued := len(sink.queue); queued != 0 {
+		t.Errorf("Expected Flush to empty the queue, %d lines left", queued)
+	}
+	for i := 0; i <= 100; i++ {
+		if !scanner.Scan() {
+			t.Fatalf("Expected line %d, got error %v", i, scanner.Err())
+		} else if expected := fmt.Sprintf(`{"n":%d}`, i); scanner.Text() != expected {
+			t.Fatalf("Expected line %d to be %s, got %s", i, expected, scanner.Text())
+		}
+	}
+}
+
+func TestNetSinkFlushWhileDisconnected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	sink, err := NewNetSink("tcp", address, spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	sink.MinBackoff = time.Hour
+	if err = sink.WriteLine(LevelInfo, []byte(`{"n":0}`)); err != nil {
+		t.Fatal(err)
+	}
+	sink.Flush()
+	data, err := os.ReadFile(spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(data) != "{\"n\":0}\n" {
+		t.Errorf("Expected the line to be spooled, got %q", data)
+	}
+}
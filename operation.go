@@ -0,0 +1,164 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Metadata keys used by operations.
+const (
+	OperationIDKey       = "op_id"
+	OperationNameKey     = "op_name"
+	ParentOperationIDKey = "parent_op_id"
+	DurationKey          = "duration_ms"
+	SlowKey              = "slow"
+	ErrorKey             = "error"
+)
+
+// Operation is a timed scope started with Logger.Begin. The start is logged at DEBUG level and
+// End logs the completion with the elapsed time.
+//
+// All entries of the operation have the op_id and op_name metadata fields. Operations started with
+// Operation.Begin or from Operation.Logger also have a parent_op_id field, so nested operations form a tree.
+type Operation struct {
+	Name     string
+	ID       string
+	ParentID string
+	Start    time.Time
+	// SlowThreshold raises the level of the completion entry from INFO to WARN if the operation took
+	// longer than this. Zero disables the threshold.
+	SlowThreshold time.Duration
+
+	parent   Logger
+	metadata map[string]interface{}
+	log      Logger
+	clock    Clock
+}
+
+// BeginOperation starts an operation on the given logger. It can be used to implement Logger.Begin
+// for custom Logger implementations.
+//
+// The fields are alternating keys and values, e.g. `"room_id", roomID, "user_id", userID`.
+func BeginOperation(log Logger, name string, fields ...interface{}) *Operation {
+	return beginOperation(log, nil, "", name, fields)
+}
+
+func beginOperation(parent Logger, parentMetadata map[string]interface{}, parentID, name string, fields []interface{}) *Operation {
+	if len(parentID) == 0 {
+		parentID, _ = parentMetadata[OperationIDKey].(string)
+	}
+	op := &Operation{
+		Name:     name,
+		ID:       generateOperationID(),
+		ParentID: parentID,
+		parent:   parent,
+		clock:    loggerClock(parent),
+	}
+	op.metadata = make(map[string]interface{}, len(parentMetadata)+len(fields)/2+3)
+	for key, value := range parentMetadata {
+		op.metadata[key] = value
+	}
+	addFields(op.metadata, fields)
+	op.metadata[OperationIDKey] = op.ID
+	op.metadata[OperationNameKey] = name
+	if len(parentID) > 0 {
+		op.metadata[ParentOperationIDKey] = parentID
+	} else {
+		delete(op.metadata, ParentOperationIDKey)
+	}
+	op.log = parent.Subm("", op.metadata)
+	op.log.Debugfln("Starting %s", name)
+	op.Start = op.clock()
+	return op
+}
+
+// loggerClock returns the clock of the BasicLogger that the given logger writes to, or time.Now for other loggers.
+func loggerClock(log Logger) Clock {
+	switch typedLog := log.(type) {
+	case *BasicLogger:
+		return typedLog.getClock()
+	case *Sublogger:
+		return typedLog.topLevel.getClock()
+	}
+	return time.Now
+}
+
+func generateOperationID() string {
+	var id [6]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// addFields adds alternating key-value pairs to the metadata. A missing value for the last key is set to nil.
+func addFields(metadata map[string]interface{}, fields []interface{}) {
+	for i := 0; i < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok {
+			key = fmt.Sprint(fields[i])
+		}
+		if i+1 < len(fields) {
+			metadata[key] = fields[i+1]
+		} else {
+			metadata[key] = nil
+		}
+	}
+}
+
+// WithSlowThreshold sets SlowThreshold and returns the operation for chaining.
+func (op *Operation) WithSlowThreshold(threshold time.Duration) *Operation {
+	op.SlowThreshold = threshold
+	return op
+}
+
+// Logger returns a logger that includes the operation metadata in all entries.
+func (op *Operation) Logger() Logger {
+	return op.log
+}
+
+// Begin starts a nested operation.
+func (op *Operation) Begin(name string, fields ...interface{}) *Operation {
+	return beginOperation(op.parent, op.metadata, op.ID, name, fields)
+}
+
+// End logs the completion of the operation with the elapsed time and returns it.
+// The entry is logged at ERROR level if err is not nil, WARN if the operation was slow and INFO otherwise.
+func (op *Operation) End(err error) time.Duration {
+	elapsed := op.clock().Sub(op.Start)
+	metadata := make(map[string]interface{}, len(op.metadata)+3)
+	for key, value := range op.metadata {
+		metadata[key] = value
+	}
+	metadata[DurationKey] = float64(elapsed) / float64(time.Millisecond)
+	level := LevelInfo
+	if op.SlowThreshold > 0 && elapsed > op.SlowThreshold {
+		metadata[SlowKey] = true
+		level = LevelWarn
+	}
+	log := op.parent.Subm("", metadata)
+	if err != nil {
+		metadata[ErrorKey] = err.Error()
+		log.Errorfln("%s failed after %s: %v", op.Name, elapsed, err)
+	} else {
+		log.Logfln(level, "Finished %s in %s", op.Name, elapsed)
+	}
+	return elapsed
+}
+
+// Begin starts a timed operation. See Operation for details.
+func (log *BasicLogger) Begin(name string, fields ...interface{}) *Operation {
+	return log.DefaultSub.Begin(name, fields...)
+}
+
+// Begin starts a timed operation. See Operation for details.
+func (log *Sublogger) Begin(name string, fields ...interface{}) *Operation {
+	return beginOperation(log, log.metadata, "", name, fields)
+}
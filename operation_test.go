@@ -0,0 +1,138 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a Clock that only moves when advanced.
+type testClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (tc *testClock) Now() time.Time {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	return tc.now
+}
+
+func (tc *testClock) Advance(d time.Duration) {
+	tc.lock.Lock()
+	tc.now = tc.now.Add(d)
+	tc.lock.Unlock()
+}
+
+func newOperationTestLogger() (*BasicLogger, *testClock, *[]Entry) {
+	log := newQuietLogger()
+	log.AddSink(&discardSink{})
+	clock := &testClock{now: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)}
+	log.SetClock(clock.Now)
+	var entries []Entry
+	log.AddHookFunc(func(entry *Entry) bool {
+		entries = append(entries, *entry)
+		return true
+	})
+	return log, clock, &entries
+}
+
+func TestOperationBeginEnd(t *testing.T) {
+	log, clock, entries := newOperationTestLogger()
+	op := log.Begin("sync", "room_id", "!meow")
+	clock.Advance(1500 * time.Millisecond)
+	if elapsed := op.End(nil); elapsed != 1500*time.Millisecond {
+		t.Errorf("Expected the operation to take 1.5s on the logger clock, got %s", elapsed)
+	}
+	if len(*entries) != 2 {
+		t.Fatalf("Expected start and end entries, got %d", len(*entries))
+	}
+	start, end := (*entries)[0], (*entries)[1]
+	if start.Level != LevelDebug || start.Message != "Starting sync" {
+		t.Errorf("Unexpected start entry %s %q", start.Level.Name, start.Message)
+	} else if end.Level != LevelInfo || end.Message != "Finished sync in 1.5s" {
+		t.Errorf("Unexpected end entry %s %q", end.Level.Name, end.Message)
+	}
+	for _, entry := range *entries {
+		if entry.Metadata[OperationIDKey] != op.ID || entry.Metadata[OperationNameKey] != "sync" || entry.Metadata["room_id"] != "!meow" {
+			t.Errorf("Missing operation metadata in %v", entry.Metadata)
+		} else if _, ok := entry.Metadata[ParentOperationIDKey]; ok {
+			t.Errorf("Unexpected parent_op_id in top-level operation: %v", entry.Metadata)
+		}
+	}
+	if duration := end.Metadata[DurationKey]; duration != float64(1500) {
+		t.Errorf("Expected duration_ms to be 1500, got %v", duration)
+	}
+}
+
+func TestOperationNested(t *testing.T) {
+	log, _, entries := newOperationTestLogger()
+	parent := log.Sub("bridge").Begin("backfill")
+	child := parent.Begin("fetch")
+	child.Logger().Infoln("Fetched")
+	child.End(nil)
+	grandchild := child.Logger().Begin("decrypt")
+	grandchild.End(nil)
+	parent.End(nil)
+
+	if child.ParentID != parent.ID {
+		t.Errorf("Expected child parent ID %s, got %s", parent.ID, child.ParentID)
+	} else if grandchild.ParentID != child.ID {
+		t.Errorf("Expected grandchild parent ID %s, got %s", child.ID, grandchild.ParentID)
+	}
+	for _, entry := range *entries {
+		var expectedParent interface{}
+		switch entry.Metadata[OperationIDKey] {
+		case child.ID:
+			expectedParent = parent.ID
+		case grandchild.ID:
+			expectedParent = child.ID
+		}
+		if entry.Metadata[ParentOperationIDKey] != expectedParent {
+			t.Errorf("Expected parent_op_id %v in %q, got %v", expectedParent, entry.Message, entry.Metadata[ParentOperationIDKey])
+		} else if entry.Module != "bridge" {
+			t.Errorf("Expected operation entries to keep the module, got %q", entry.Module)
+		}
+	}
+}
+
+func TestOperationSlowThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		elapsed   time.Duration
+		err       error
+		level     Level
+		slow      bool
+	}{
+		{"NoThreshold", 0, time.Hour, nil, LevelInfo, false},
+		{"Fast", time.Second, 500 * time.Millisecond, nil, LevelInfo, false},
+		{"AtThreshold", time.Second, time.Second, nil, LevelInfo, false},
+		{"Slow", time.Second, 2 * time.Second, nil, LevelWarn, true},
+		{"SlowError", time.Second, 2 * time.Second, errors.New("meow"), LevelError, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log, clock, entries := newOperationTestLogger()
+			op := log.Begin("sync").WithSlowThreshold(test.threshold)
+			clock.Advance(test.elapsed)
+			op.End(test.err)
+			end := (*entries)[len(*entries)-1]
+			if end.Level != test.level {
+				t.Errorf("Expected level %s, got %s", test.level.Name, end.Level.Name)
+			} else if slow, _ := end.Metadata[SlowKey].(bool); slow != test.slow {
+				t.Errorf("Expected slow=%t, got %v", test.slow, end.Metadata[SlowKey])
+			} else if test.err != nil && end.Metadata[ErrorKey] != test.err.Error() {
+				t.Errorf("Expected error metadata %q, got %v", test.err, end.Metadata[ErrorKey])
+			}
+		})
+	}
+}
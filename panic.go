@@ -0,0 +1,120 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+// PanicAction is what Recover does after logging a panic.
+type PanicAction int
+
+const (
+	// PanicRepanic panics again with the same value after logging.
+	PanicRepanic PanicAction = iota
+	// PanicExit exits the process after logging.
+	PanicExit
+	// PanicSwallow only logs the panic and lets the function return normally.
+	PanicSwallow
+)
+
+// RecoverOptions configures Logger.Recover.
+type RecoverOptions struct {
+	// Level is the level the panic is logged at. Defaults to LevelFatal.
+	Level Level
+	// Action is what to do after the panic has been logged and the outputs flushed.
+	Action PanicAction
+	// ExitCode is the exit code used with PanicExit. Zero means 2, the exit code of an unrecovered panic.
+	ExitCode int
+}
+
+// SinkFlusher is implemented by sinks that buffer lines, such as HTTPSink.
+type SinkFlusher interface {
+	Flush()
+}
+
+// HandlePanic logs a recovered panic value with the current stack, flushes the logger if it has
+// a Flush method and then performs the configured action. It can be used to implement Logger.Recover
+// for custom Logger implementations, which must call recover() themselves.
+func HandlePanic(log Logger, recovered interface{}, opts RecoverOptions) {
+	level := opts.Level
+	if len(level.Name) == 0 {
+		level = LevelFatal
+	}
+	log.Logfln(level, "Panic: %v\n%s", recovered, debug.Stack())
+	if flusher, ok := log.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	switch opts.Action {
+	case PanicExit:
+		exitCode := opts.ExitCode
+		if exitCode == 0 {
+			exitCode = 2
+		}
+		os.Exit(exitCode)
+	case PanicSwallow:
+	default:
+		panic(recovered)
+	}
+}
+
+// Flush syncs the log file to disk and flushes sinks that implement SinkFlusher.
+func (log *BasicLogger) Flush() error {
+	var err error
+	log.writerLock.Lock()
+	if log.writer != nil {
+		err = log.writer.Sync()
+	}
+	log.writerLock.Unlock()
+	log.sinkLock.Lock()
+	sinks := log.sinks
+	log.sinkLock.Unlock()
+	for _, sink := range sinks {
+		if flusher, ok := sink.(SinkFlusher); ok {
+			flusher.Flush()
+		}
+	}
+	return err
+}
+
+// Flush flushes the outputs of the top-level logger.
+func (log *Sublogger) Flush() error {
+	return log.topLevel.Flush()
+}
+
+// Recover logs a panic and handles it according to the options. It must be called directly with defer:
+//
+//	defer log.Recover(maulogger.RecoverOptions{Action: maulogger.PanicSwallow})
+func (log *BasicLogger) Recover(opts RecoverOptions) {
+	if recovered := recover(); recovered != nil {
+		HandlePanic(log, recovered, opts)
+	}
+}
+
+// Recover logs a panic and handles it according to the options. It must be called directly with defer.
+func (log *Sublogger) Recover(opts RecoverOptions) {
+	if recovered := recover(); recovered != nil {
+		HandlePanic(log, recovered, opts)
+	}
+}
+
+// Go runs the function in a new goroutine. Panics are logged at LevelFatal and the outputs flushed
+// before the panic continues. Use Recover in the goroutine directly for other behavior.
+func (log *BasicLogger) Go(fn func()) {
+	log.DefaultSub.Go(fn)
+}
+
+// Go runs the function in a new goroutine. Panics are logged at LevelFatal and the outputs flushed
+// before the panic continues. Use Recover in the goroutine directly for other behavior.
+func (log *Sublogger) Go(fn func()) {
+	go func() {
+		defer log.Recover(RecoverOptions{})
+		fn()
+	}()
+}
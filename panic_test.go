@@ -0,0 +1,126 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type flushCountingSink struct {
+	discardSink
+	flushes atomic.Int32
+}
+
+func (fcs *flushCountingSink) Flush() {
+	fcs.flushes.Add(1)
+}
+
+func newPanicTestLogger() (*BasicLogger, *flushCountingSink, *[]Entry) {
+	log := newQuietLogger()
+	sink := &flushCountingSink{}
+	log.AddSink(sink)
+	var entries []Entry
+	log.AddHookFunc(func(entry *Entry) bool {
+		entries = append(entries, *entry)
+		return true
+	})
+	return log, sink, &entries
+}
+
+func TestRecoverSwallow(t *testing.T) {
+	tests := []struct {
+		name  string
+		level Level
+		sub   bool
+		want  Level
+	}{
+		{"DefaultLevel", Level{}, false, LevelFatal},
+		{"CustomLevel", LevelError, false, LevelError},
+		{"Sublogger", LevelWarn, true, LevelWarn},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log, sink, entries := newPanicTestLogger()
+			var logger Logger = log
+			if test.sub {
+				logger = log.Sub("worker")
+			}
+			func() {
+				defer logger.Recover(RecoverOptions{Level: test.level, Action: PanicSwallow})
+				panic("meow")
+			}()
+			if len(*entries) != 1 {
+				t.Fatalf("Expected 1 entry, got %d", len(*entries))
+			}
+			entry := (*entries)[0]
+			if entry.Level != test.want {
+				t.Errorf("Expected level %s, got %s", test.want.Name, entry.Level.Name)
+			} else if !strings.HasPrefix(entry.Message, "Panic: meow\n") || !strings.Contains(entry.Message, "panic_test.go") {
+				t.Errorf("Expected the panic value and stack in the message, got %q", entry.Message)
+			} else if test.sub && entry.Module != "worker" {
+				t.Errorf("Expected the sublogger module, got %q", entry.Module)
+			} else if sink.flushes.Load() != 1 {
+				t.Errorf("Expected the sink to be flushed once, got %d", sink.flushes.Load())
+			}
+		})
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	log, _, entries := newPanicTestLogger()
+	var repanicked interface{}
+	func() {
+		defer func() {
+			repanicked = recover()
+		}()
+		defer log.Recover(RecoverOptions{})
+		panic("meow")
+	}()
+	if repanicked != "meow" {
+		t.Errorf("Expected the panic to continue with the same value, got %v", repanicked)
+	} else if len(*entries) != 1 || (*entries)[0].Level != LevelFatal {
+		t.Errorf("Expected the panic to be logged at FATAL first, got %v", *entries)
+	}
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	log, sink, entries := newPanicTestLogger()
+	func() {
+		defer log.Recover(RecoverOptions{Action: PanicSwallow})
+	}()
+	if len(*entries) != 0 || sink.flushes.Load() != 0 {
+		t.Errorf("Expected nothing to happen without a panic, got %d entries and %d flushes", len(*entries), sink.flushes.Load())
+	}
+}
+
+func TestGoLogsPanic(t *testing.T) {
+	if os.Getenv("MAULOGGER_TEST_GO_PANIC") == "1" {
+		log := Createm(map[string]interface{}{}).(*BasicLogger)
+		log.PrintLevel = LevelDebug.Severity
+		done := make(chan struct{})
+		log.Sub("worker").Go(func() {
+			defer close(done)
+			panic("meow")
+		})
+		<-done
+		select {}
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestGoLogsPanic$")
+	cmd.Env = append(os.Environ(), "MAULOGGER_TEST_GO_PANIC=1")
+	output, err := cmd.CombinedOutput()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected the panic to crash the process with exit code 2, got %v: %s", err, output)
+	} else if !strings.Contains(string(output), "[worker/FATAL] Panic: meow") {
+		t.Errorf("Expected the panic to be logged before crashing, got %s", output)
+	}
+}
@@ -0,0 +1,225 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// DefaultRedactionMask is the replacement text used by Redactor if Mask is empty.
+const DefaultRedactionMask = "[REDACTED]"
+
+// Redactor masks sensitive data in log lines before they're written to an output.
+type Redactor struct {
+	// Keys is a list of metadata key names or glob patterns (e.g. "password" or "*token*").
+	// Matching is case-insensitive and also applies to nested maps and to JSON objects embedded
+	// in messages, such as the ones created by maulogadapt.ZeroMauLog.
+	Keys []string
+	// Patterns are regular expressions whose matches are masked in messages and string metadata values.
+	Patterns []*regexp.Regexp
+	// Mask is the replacement text. Defaults to DefaultRedactionMask.
+	Mask string
+}
+
+// NewRedactor creates a Redactor that masks the given metadata keys.
+func NewRedactor(keys ...string) *Redactor {
+	return &Redactor{Keys: keys}
+}
+
+// AddPattern compiles the given regular expression and adds it to the message scrubbing patterns.
+func (r *Redactor) AddPattern(pattern string) error {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	r.Patterns = append(r.Patterns, compiled)
+	return nil
+}
+
+func (r *Redactor) mask() string {
+	if len(r.Mask) == 0 {
+		return DefaultRedactionMask
+	}
+	return r.Mask
+}
+
+// MatchKey checks if the given metadata key should be masked.
+func (r *Redactor) MatchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.Keys {
+		pattern = strings.ToLower(pattern)
+		if pattern == key {
+			return true
+		} else if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonPairRegex matches "key": value pairs in text that isn't valid JSON. Object and array values
+// aren't matched, so the pairs inside them are checked separately.
+var jsonPairRegex = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"|[^\s,}\]{\[]+)`)
+
+// RedactMessage masks all pattern matches and values of sensitive keys in JSON objects in the given message.
+func (r *Redactor) RedactMessage(message string) string {
+	if len(r.Keys) > 0 && strings.IndexByte(message, '"') >= 0 {
+		message = r.redactEmbeddedJSON(message)
+		message = jsonPairRegex.ReplaceAllStringFunc(message, func(pair string) string {
+			parts := jsonPairRegex.FindStringSubmatch(pair)
+			if !r.MatchKey(parts[1]) {
+				return pair
+			}
+			return `"` + parts[1] + `"` + parts[2] + `"` + r.mask() + `"`
+		})
+	}
+	for _, pattern := range r.Patterns {
+		message = pattern.ReplaceAllLiteralString(message, r.mask())
+	}
+	return message
+}
+
+// redactEmbeddedJSON finds JSON objects in the message and masks the values of sensitive keys at any depth.
+func (r *Redactor) redactEmbeddedJSON(message string) string {
+	var out strings.Builder
+	written := 0
+	for i := 0; i < len(message); i++ {
+		if message[i] != '{' {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(message[i:]))
+		var raw json.RawMessage
+		if decoder.Decode(&raw) != nil {
+			continue
+		}
+		end := i + int(decoder.InputOffset())
+		object := message[i:end]
+		paths := r.sensitivePaths(gjson.Parse(object), "", nil)
+		for _, keyPath := range paths {
+			object, _ = sjson.Set(object, keyPath, r.mask())
+		}
+		if len(paths) > 0 {
+			out.WriteString(message[written:i])
+			out.WriteString(object)
+			written = end
+		}
+		i = end - 1
+	}
+	if written == 0 {
+		return message
+	}
+	out.WriteString(message[written:])
+	return out.String()
+}
+
+var jsonPathEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`, `\`, `\\`)
+
+// sensitivePaths returns the sjson paths of all values with sensitive keys in the given JSON value.
+func (r *Redactor) sensitivePaths(value gjson.Result, prefix string, paths []string) []string {
+	if !value.IsObject() && !value.IsArray() {
+		return paths
+	}
+	index := 0
+	value.ForEach(func(key, item gjson.Result) bool {
+		var keyPath string
+		if value.IsArray() {
+			keyPath = strconv.Itoa(index)
+			index++
+		} else {
+			keyPath = jsonPathEscaper.Replace(key.Str)
+		}
+		if len(prefix) > 0 {
+			keyPath = prefix + "." + keyPath
+		}
+		if value.IsObject() && r.MatchKey(key.Str) {
+			paths = append(paths, keyPath)
+		} else {
+			paths = r.sensitivePaths(item, keyPath, paths)
+		}
+		return true
+	})
+	return paths
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case string:
+		return r.RedactMessage(typedValue)
+	case map[string]interface{}:
+		return r.RedactMetadata(typedValue)
+	case []interface{}:
+		redacted := make([]interface{}, len(typedValue))
+		for i, item := range typedValue {
+			redacted[i] = r.redactValue(item)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// RedactMetadata returns a copy of the given metadata with sensitive keys masked and patterns scrubbed from string values.
+func (r *Redactor) RedactMetadata(metadata map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if r.MatchKey(key) {
+			redacted[key] = r.mask()
+		} else {
+			redacted[key] = r.redactValue(value)
+		}
+	}
+	return redacted
+}
+
+// redact returns the log line with sensitive data masked.
+// If the redactor is nil, the line is returned as-is.
+func (r *Redactor) redact(line logLine) logLine {
+	if r == nil {
+		return line
+	}
+	line.Message = r.RedactMessage(line.Message)
+	line.Metadata = r.RedactMetadata(line.Metadata)
+	return line
+}
+
+// SetRedactor sets the same redactor for all outputs.
+func (log *BasicLogger) SetRedactor(r *Redactor) {
+	log.configLock.Lock()
+	log.FileRedactor = r
+	log.StdoutRedactor = r
+	log.SinkRedactor = r
+	log.configLock.Unlock()
+}
+
+// SetFileRedactor changes the redactor used for the log file.
+func (log *BasicLogger) SetFileRedactor(r *Redactor) {
+	log.configLock.Lock()
+	log.FileRedactor = r
+	log.configLock.Unlock()
+}
+
+// SetStdoutRedactor changes the redactor used for stdout/stderr.
+func (log *BasicLogger) SetStdoutRedactor(r *Redactor) {
+	log.configLock.Lock()
+	log.StdoutRedactor = r
+	log.configLock.Unlock()
+}
+
+// SetSinkRedactor changes the redactor used for sinks.
+func (log *BasicLogger) SetSinkRedactor(r *Redactor) {
+	log.configLock.Lock()
+	log.SinkRedactor = r
+	log.configLock.Unlock()
+}
@@ -0,0 +1,76 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedactMessage(t *testing.T) {
+	r := NewRedactor("*token*", "password", "credentials")
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"Flat", `Login {"user":"meow","password":"hunter2"}`, `Login {"user":"meow","password":"[REDACTED]"}`},
+		{"Nested", `{"auth":{"access_token":"secret"}}`, `{"auth":{"access_token":"[REDACTED]"}}`},
+		{"DeeplyNested", `Sync {"a":{"b":[{"c":{"refresh_token":"secret","ok":1}}]}}`, `Sync {"a":{"b":[{"c":{"refresh_token":"[REDACTED]","ok":1}}]}}`},
+		{"ObjectValue", `{"credentials":{"user":"meow","pass":"secret"},"n":1}`, `{"credentials":"[REDACTED]","n":1}`},
+		{"NumberValue", `{"user_token":12345}`, `{"user_token":"[REDACTED]"}`},
+		{"SpecialKeyCharacters", `{"a.b":{"x*token":"secret"}}`, `{"a.b":{"x*token":"[REDACTED]"}}`},
+		{"InvalidJSON", `Got "access_token": "secret", "auth": {"password": "hunter2"`, `Got "access_token": "[REDACTED]", "auth": {"password": "[REDACTED]"`},
+		{"NoSecrets", `Hello {"user":"meow"} world`, `Hello {"user":"meow"} world`},
+		{"MultipleObjects", `{"token":"a"} and {"b":{"token":"c"}}`, `{"token":"[REDACTED]"} and {"b":{"token":"[REDACTED]"}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if redacted := r.RedactMessage(test.message); redacted != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, redacted)
+			}
+		})
+	}
+}
+
+func TestRedactMetadata(t *testing.T) {
+	r := NewRedactor("*token*")
+	redacted := r.RedactMetadata(map[string]interface{}{
+		"user": "meow",
+		"auth": map[string]interface{}{"access_token": "secret"},
+		"raw":  `{"auth":{"access_token":"secret"}}`,
+	})
+	if redacted["user"] != "meow" {
+		t.Errorf("Unexpected user value %v", redacted["user"])
+	} else if nested := redacted["auth"].(map[string]interface{}); nested["access_token"] != DefaultRedactionMask {
+		t.Errorf("Nested metadata not redacted: %v", nested)
+	} else if redacted["raw"] != `{"auth":{"access_token":"[REDACTED]"}}` {
+		t.Errorf("JSON in string value not redacted: %v", redacted["raw"])
+	}
+}
+
+func TestPerOutputRedactor(t *testing.T) {
+	log := Createm(map[string]interface{}{}).(*BasicLogger)
+	file := openTestFile(t, "redact.log")
+	log.SetWriter(file)
+	defer log.Close()
+	log.SetStdoutRedactor(NewRedactor("password"))
+	output := captureStdout(t, func() {
+		log.Infoln(`Login {"password":"hunter2"}`)
+	})
+	if !strings.Contains(output, `"password":"[REDACTED]"`) {
+		t.Errorf("Expected the password to be redacted on stdout, got %q", output)
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(data), `"password":"hunter2"`) {
+		t.Errorf("Expected the file to be unaffected by the stdout redactor, got %q", data)
+	}
+}
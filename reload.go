@@ -0,0 +1,226 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// fileChanged checks if the log file needs to be reopened. If no config with a file path has been
+// applied yet, a file opened manually with OpenFile or SetWriter is only replaced if the config has a path.
+func (cfg *Config) fileChanged(prev *Config) bool {
+	if prev == nil || len(prev.File.PathFormat) == 0 {
+		return len(cfg.File.PathFormat) > 0
+	}
+	return prev.File.PathFormat != cfg.File.PathFormat ||
+		prev.File.TimeFormat != cfg.File.TimeFormat ||
+		prev.File.Mode != cfg.File.Mode ||
+		prev.Timezone != cfg.Timezone
+}
+
+func (cfg *Config) sinksChanged(prev *Config) bool {
+	return prev == nil || !reflect.DeepEqual(prev.Sinks, cfg.Sinks)
+}
+
+// Reload applies the given config to the logger.
+//
+// All settings are swapped at once: entries logged during the reload use either the old or the new
+// settings, never a mix of both. The log file is only reopened if the file path, time format or mode
+// changed, and sinks are only recreated if their config changed. A log file opened manually with
+// OpenFile or SetWriter is kept as long as no config with a file path is applied, and sinks added
+// manually with AddSink are always kept. Metadata is only replaced if the config has any.
+//
+// If the config is invalid or opening the new outputs fails, the logger is left unchanged.
+func (log *BasicLogger) Reload(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	log.reloadLock.Lock()
+	defer log.reloadLock.Unlock()
+	prev := log.appliedConfig
+
+	consoleLevel, err := cfg.Console.level()
+	if err != nil {
+		return err
+	}
+	var moduleLevels map[string]int
+	if len(cfg.ModuleLevels) > 0 {
+		moduleLevels = make(map[string]int, len(cfg.ModuleLevels))
+		for module, levelName := range cfg.ModuleLevels {
+			level, err := ParseLevel(levelName)
+			if err != nil {
+				return fmt.Errorf("module_levels.%s: %w", module, err)
+			}
+			moduleLevels[module] = level.Severity
+		}
+	}
+
+	fileChanged := cfg.fileChanged(prev)
+	fileMode, _ := parseFileMode(cfg.File.Mode)
+	location, _ := loadLocation(cfg.Timezone)
+	pathFormat := cfg.File.PathFormat
+	fileFormat := func(now string, i int) string { return fmt.Sprintf(pathFormat, now, i) }
+	var newWriter *os.File
+	if fileChanged && len(pathFormat) > 0 {
+		log.configLock.RLock()
+		now := currentTime(log.Clock, location)
+		log.configLock.RUnlock()
+		newWriter, err = openLogFile(now, cfg.File.TimeFormat, fileFormat, fileMode)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+	}
+
+	sinksChanged := cfg.sinksChanged(prev)
+	var newSinks []Sink
+	if sinksChanged {
+		for i := range cfg.Sinks {
+			sink, err := cfg.Sinks[i].build()
+			if err != nil {
+				for _, createdSink := range newSinks {
+					_ = createdSink.Close()
+				}
+				if newWriter != nil {
+					_ = newWriter.Close()
+				}
+				return fmt.Errorf("failed to create sinks[%d]: %w", i, err)
+			}
+			newSinks = append(newSinks, sink)
+		}
+	}
+
+	var redactor *Redactor
+	if cfg.Redact != nil {
+		redactor = NewRedactor(cfg.Redact.Keys...)
+		redactor.Mask = cfg.Redact.Mask
+		for _, pattern := range cfg.Redact.Patterns {
+			_ = redactor.AddPattern(pattern)
+		}
+	}
+
+	var oldWriter *os.File
+	var oldSinks []Sink
+	log.configLock.Lock()
+	log.TimeFormat = cfg.TimeFormat
+	log.PrintLevel = consoleLevel.Severity
+	log.ConsoleDisabled = cfg.Console.Disabled
+	log.JSONStdout = cfg.Console.JSON
+	log.StdoutMetadataLine = cfg.Console.MetadataLine
+	log.TextPolicy = cfg.Text
+	log.Location = location
+	log.AutoFields = cfg.AutoFields
+	log.FileLimits = cfg.File.Limits
+	log.StdoutLimits = cfg.Console.Limits
+	log.SinkLimits = cfg.SinkLimits
+	log.JSONFile = cfg.File.JSON
+	log.ModuleLevels = moduleLevels
+	log.FileRedactor = redactor
+	log.StdoutRedactor = redactor
+	log.SinkRedactor = redactor
+	if cfg.Metadata != nil {
+		log.metadata = cfg.Metadata
+	}
+	if len(cfg.File.TimeFormat) > 0 {
+		log.FileTimeFormat = cfg.File.TimeFormat
+	}
+	if len(pathFormat) > 0 {
+		log.FileFormat = fileFormat
+	}
+	log.writerLock.Lock()
+	// FileMode is also read by reopenFile, which only holds writerLock
+	log.FileMode = fileMode
+	if fileChanged {
+		oldWriter = log.writer
+		log.writer = newWriter
+		log.hasFile.Store(newWriter != nil)
+		log.fileErrors = fileErrorState{}
+	}
+	log.writerLock.Unlock()
+	if sinksChanged {
+		log.sinkLock.Lock()
+		oldSinks = log.configSinks
+		keptSinks := make([]Sink, 0, len(log.sinks)-len(oldSinks)+len(newSinks))
+		for _, sink := range log.sinks {
+			if !containsSink(oldSinks, sink) {
+				keptSinks = append(keptSinks, sink)
+			}
+		}
+		log.sinks = append(keptSinks, newSinks...)
+		log.hasSinks.Store(len(log.sinks) > 0)
+		log.configSinks = newSinks
+		log.sinkLock.Unlock()
+	}
+	cfgCopy := *cfg
+	log.appliedConfig = &cfgCopy
+	log.configLock.Unlock()
+
+	if oldWriter != nil {
+		_ = oldWriter.Close()
+	}
+	for _, sink := range oldSinks {
+		_ = sink.Close()
+	}
+	return nil
+}
+
+func containsSink(sinks []Sink, sink Sink) bool {
+	for _, s := range sinks {
+		if s == sink {
+			return true
+		}
+	}
+	return false
+}
+
+const defaultWatchInterval = 5 * time.Second
+
+// WatchConfig polls the given config file and reloads the logger whenever the file changes.
+// Environment variables are applied on top of the file like Config.LoadEnv does. Reload errors are
+// logged, and the previous config stays in effect until the file is fixed.
+//
+// WatchConfig blocks until the context is canceled. Zero or less as the interval means the default of 5 seconds.
+func (log *BasicLogger) WatchConfig(ctx context.Context, path string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	var lastModTime time.Time
+	var lastSize int64
+	if stat, err := os.Stat(path); err == nil {
+		lastModTime, lastSize = stat.ModTime(), stat.Size()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		stat, err := os.Stat(path)
+		if err != nil || (stat.ModTime().Equal(lastModTime) && stat.Size() == lastSize) {
+			continue
+		}
+		lastModTime, lastSize = stat.ModTime(), stat.Size()
+		cfg, err := LoadConfig(path)
+		if err == nil {
+			err = cfg.LoadEnv()
+		}
+		if err == nil {
+			err = log.Reload(cfg)
+		}
+		if err != nil {
+			log.Errorfln("Failed to reload logger config from %s: %v", path, err)
+		} else {
+			log.Infofln("Reloaded logger config from %s", path)
+		}
+	}
+}
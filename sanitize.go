@@ -0,0 +1,196 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// NewlinePolicy controls how newlines inside messages are written in text mode.
+//
+// Multi-line entries are always written to each output with a single write, so lines of other entries
+// can't end up between the continuation lines.
+type NewlinePolicy int
+
+const (
+	// NewlineIndent indents continuation lines, so they can't be mistaken for new entries.
+	NewlineIndent NewlinePolicy = iota
+	// NewlineEscape writes newlines as a literal `\n`, keeping every entry on one line.
+	NewlineEscape
+	// NewlineKeep writes newlines as-is. Messages containing user input can forge fake entries.
+	NewlineKeep
+	// NewlineGutter prefixes continuation lines with a `| ` gutter aligned with the start of the message.
+	NewlineGutter
+	// NewlineHeader prefixes continuation lines with the `[time] [module/LEVEL]` header of the entry and a `| ` gutter.
+	NewlineHeader
+)
+
+var newlinePolicyNames = []string{"indent", "escape", "keep", "gutter", "header"}
+
+// ControlCharPolicy controls how control characters inside messages are written in text mode.
+//
+// Control characters are C0 and C1 control codes other than tab and newline, DEL, invalid UTF-8
+// and the Unicode bidirectional overrides, which can all be used to hide or rewrite text in terminals.
+type ControlCharPolicy int
+
+const (
+	// ControlCharEscape writes control characters as Go escape sequences, e.g. `\x1b`.
+	ControlCharEscape ControlCharPolicy = iota
+	// ControlCharStrip removes control characters.
+	ControlCharStrip
+	// ControlCharKeep writes control characters as-is.
+	ControlCharKeep
+)
+
+var controlCharPolicyNames = []string{"escape", "strip", "keep"}
+
+// TextPolicy controls how messages are sanitized and laid out in text mode. JSON output is always escaped by the encoder.
+// The zero value indents continuation lines and escapes control characters.
+type TextPolicy struct {
+	Newlines     NewlinePolicy     `json:"newlines,omitempty" yaml:"newlines,omitempty"`
+	ControlChars ControlCharPolicy `json:"control_chars,omitempty" yaml:"control_chars,omitempty"`
+}
+
+func (np NewlinePolicy) String() string {
+	if np < 0 || int(np) >= len(newlinePolicyNames) {
+		return strconv.Itoa(int(np))
+	}
+	return newlinePolicyNames[np]
+}
+
+func (np NewlinePolicy) MarshalText() ([]byte, error) {
+	return []byte(np.String()), nil
+}
+
+func (np *NewlinePolicy) UnmarshalText(text []byte) error {
+	for i, name := range newlinePolicyNames {
+		if name == string(text) {
+			*np = NewlinePolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown newline policy %q", text)
+}
+
+func (cp ControlCharPolicy) String() string {
+	if cp < 0 || int(cp) >= len(controlCharPolicyNames) {
+		return strconv.Itoa(int(cp))
+	}
+	return controlCharPolicyNames[cp]
+}
+
+func (cp ControlCharPolicy) MarshalText() ([]byte, error) {
+	return []byte(cp.String()), nil
+}
+
+func (cp *ControlCharPolicy) UnmarshalText(text []byte) error {
+	for i, name := range controlCharPolicyNames {
+		if name == string(text) {
+			*cp = ControlCharPolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown control character policy %q", text)
+}
+
+func isControlChar(r rune) bool {
+	return r < ' ' || (r >= 0x7f && r <= 0x9f) ||
+		(r >= 0x202a && r <= 0x202e) || (r >= 0x2066 && r <= 0x2069)
+}
+
+// AppendMessage appends the message to the buffer with newlines and control characters handled according to the policy.
+//
+// For NewlineGutter and NewlineHeader, the text after the last newline in buf is used as the header of the entry.
+func (tp TextPolicy) AppendMessage(buf []byte, msg string) []byte {
+	return tp.appendMessage(buf, msg, bytes.LastIndexByte(buf, '\n')+1)
+}
+
+// appendMessage appends the message to the buffer. The header of the entry starts at lineStart and
+// ends at the current end of buf.
+func (tp TextPolicy) appendMessage(buf []byte, msg string, lineStart int) []byte {
+	headerEnd := len(buf)
+	start := 0
+	for i := 0; i < len(msg); {
+		r, size := rune(msg[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(msg[i:])
+			if r == utf8.RuneError && size == 1 {
+				r = rune(msg[i])
+			} else if !isControlChar(r) {
+				i += size
+				continue
+			}
+		} else if !isControlChar(r) || r == '\t' {
+			i++
+			continue
+		}
+		buf = append(buf, msg[start:i]...)
+		if r == '\r' && i+1 < len(msg) && msg[i+1] == '\n' {
+			// Treat CRLF as a single newline
+			i++
+			size = 1
+			r = '\n'
+		}
+		if r == '\n' {
+			buf = tp.appendNewline(buf, lineStart, headerEnd)
+		} else {
+			buf = tp.appendControlChar(buf, r, msg[i:i+size])
+		}
+		i += size
+		start = i
+	}
+	return append(buf, msg[start:]...)
+}
+
+func (tp TextPolicy) appendNewline(buf []byte, lineStart, headerEnd int) []byte {
+	switch tp.Newlines {
+	case NewlineEscape:
+		return append(buf, `\n`...)
+	case NewlineKeep:
+		return append(buf, '\n')
+	case NewlineGutter:
+		buf = append(buf, '\n')
+		for width := utf8.RuneCount(buf[lineStart:headerEnd]) - 2; width > 0; width-- {
+			buf = append(buf, ' ')
+		}
+		return append(buf, "| "...)
+	case NewlineHeader:
+		buf = append(buf, '\n')
+		buf = append(buf, buf[lineStart:headerEnd]...)
+		return append(buf, "| "...)
+	default:
+		return append(buf, "\n\t"...)
+	}
+}
+
+func (tp TextPolicy) appendControlChar(buf []byte, r rune, raw string) []byte {
+	switch tp.ControlChars {
+	case ControlCharStrip:
+		return buf
+	case ControlCharKeep:
+		return append(buf, raw...)
+	}
+	if len(raw) == 1 {
+		// ASCII control characters and invalid UTF-8 bytes
+		quoted := strconv.AppendQuote(nil, raw)
+		return append(buf, quoted[1:len(quoted)-1]...)
+	}
+	buf = append(buf, `\u`...)
+	const hex = "0123456789abcdef"
+	return append(buf, hex[r>>12&0xf], hex[r>>8&0xf], hex[r>>4&0xf], hex[r&0xf])
+}
+
+// SetTextPolicy changes how newlines and control characters in messages are written in text mode.
+func (log *BasicLogger) SetTextPolicy(policy TextPolicy) {
+	log.configLock.Lock()
+	log.TextPolicy = policy
+	log.configLock.Unlock()
+}
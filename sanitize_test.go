@@ -0,0 +1,88 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendMessage(t *testing.T) {
+	const header = "[15:04] [INFO] "
+	tests := []struct {
+		name     string
+		policy   TextPolicy
+		message  string
+		expected string
+	}{
+		{"Plain", TextPolicy{}, "Hello world", "Hello world"},
+		{"Tab", TextPolicy{}, "a\tb", "a\tb"},
+		{"Indent", TextPolicy{}, "a\nb", "a\n\tb"},
+		{"IndentCRLF", TextPolicy{}, "a\r\nb", "a\n\tb"},
+		{"LoneCR", TextPolicy{}, "a\rb", `a\rb`},
+		{"Escape", TextPolicy{Newlines: NewlineEscape}, "a\nb\r\nc", `a\nb\nc`},
+		{"Keep", TextPolicy{Newlines: NewlineKeep}, "a\r\nb", "a\nb"},
+		{"Gutter", TextPolicy{Newlines: NewlineGutter}, "a\nb", "a\n             | b"},
+		{"GutterCRLF", TextPolicy{Newlines: NewlineGutter}, "a\r\nb\r\nc", "a\n             | b\n             | c"},
+		{"Header", TextPolicy{Newlines: NewlineHeader}, "a\nb", "a\n" + header + "| b"},
+		{"EscapeControl", TextPolicy{}, "a\x1b[31mb\x00", `a\x1b[31mb\x00`},
+		{"EscapeC1", TextPolicy{}, "a\u009bb", `a\u009bb`},
+		{"EscapeBidi", TextPolicy{}, "a\u202eb", `a\u202eb`},
+		{"EscapeDEL", TextPolicy{}, "a\x7fb", `a\x7fb`},
+		{"InvalidUTF8", TextPolicy{}, "a\xffb\xc3", `a\xffb\xc3`},
+		{"ValidUTF8", TextPolicy{}, "mjäu 🐈", "mjäu 🐈"},
+		{"Strip", TextPolicy{ControlChars: ControlCharStrip}, "a\x1b[31mb\xff\u202e", "a[31mb"},
+		{"KeepControl", TextPolicy{ControlChars: ControlCharKeep}, "a\x1bb\xff", "a\x1bb\xff"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := string(test.policy.AppendMessage([]byte(header), test.message))
+			if expected := header + test.expected; output != expected {
+				t.Errorf("Expected %q, got %q", expected, output)
+			}
+		})
+	}
+}
+
+func TestGutterWidthWithColor(t *testing.T) {
+	entry := &Entry{
+		Time:    time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:   LevelWarn,
+		Module:  "bridge",
+		Message: "first\nsecond",
+	}
+	line := newLogLine("15:04:05", entry)
+	line.policy = TextPolicy{Newlines: NewlineGutter}
+	output := string(line.appendTextLine(nil, LevelWarn, true, false))
+	expected := LevelWarn.GetColor() + "[15:04:05] [bridge/WARN] first\n                       | second" + LevelWarn.GetReset() + "\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestForgedEntryInMessage(t *testing.T) {
+	forged := "Login failed\n[2023-01-02 15:04:05] [ERROR] Database wiped\r\n[2023-01-02 15:04:06] [INFO] ok"
+	policies := []TextPolicy{
+		{Newlines: NewlineIndent},
+		{Newlines: NewlineEscape},
+		{Newlines: NewlineGutter},
+		{Newlines: NewlineHeader},
+	}
+	for _, policy := range policies {
+		t.Run(policy.Newlines.String(), func(t *testing.T) {
+			output := string(policy.AppendMessage([]byte("[2023-01-02 15:04:05] [WARN] "), forged))
+			lines := strings.Split(output, "\n")
+			for _, line := range lines[1:] {
+				if strings.HasPrefix(line, "[2023-01-02 15:04:05] [ERROR]") || strings.HasPrefix(line, "[2023-01-02 15:04:06] [INFO]") {
+					t.Errorf("Message forged a new entry: %q", output)
+				}
+			}
+		})
+	}
+}
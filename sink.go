@@ -0,0 +1,102 @@
+// mauLogger - A logger for Go programs
+// Copyright (c) 2023 Tulir Asokan
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package maulogger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Sink is an additional output for log lines.
+//
+// Sinks receive every line regardless of PrintLevel. The data is the JSON encoding of the line
+// (the same format as JSONFile uses) without a trailing newline, and must not be retained after
+// WriteLine returns.
+type Sink interface {
+	WriteLine(level Level, data []byte) error
+	Close() error
+}
+
+// DroppedError is returned by sinks that had to drop lines, e.g. because a queue or spool was full.
+//
+// Drops are counted with MetricsCollector.EntryDropped instead of WriteError. Count is the number of
+// lines that were dropped, which may be more than one if a whole batch was discarded; all of them are
+// attributed to the level and module of the line that was being written.
+type DroppedError struct {
+	Count  int
+	Reason error
+}
+
+func (err DroppedError) Error() string {
+	if err.Count == 1 {
+		return fmt.Sprintf("dropped log line: %v", err.Reason)
+	}
+	return fmt.Sprintf("dropped %d log lines: %v", err.Count, err.Reason)
+}
+
+func (err DroppedError) Unwrap() error {
+	return err.Reason
+}
+
+// AddSink adds a sink that will receive all future log lines.
+func (log *BasicLogger) AddSink(sink Sink) {
+	log.sinkLock.Lock()
+	log.sinks = append(log.sinks, sink)
+	log.hasSinks.Store(true)
+	log.sinkLock.Unlock()
+}
+
+func (log *BasicLogger) writeSinks(cfg *settings, buf *lineBuffer, level Level, message logLine) {
+	log.sinkLock.Lock()
+	defer log.sinkLock.Unlock()
+	if len(log.sinks) == 0 {
+		return
+	}
+	data, err := buf.encodeLimited(cfg.sinkRedactor.redact(message), level, outputFormat{json: true}, cfg.sinkLimits)
+	data = bytes.TrimSuffix(data, []byte{'\n'})
+	if err != nil {
+		log.reportError("Failed to encode log line for sinks:", err)
+		return
+	}
+	for _, sink := range log.sinks {
+		if err = sink.WriteLine(level, data); err != nil {
+			var dropped DroppedError
+			if errors.As(err, &dropped) {
+				cfg.countDropped(level, message.Module, dropped.Count)
+			} else {
+				cfg.countWriteError(OutputSink, err)
+			}
+			log.reportError("Failed to write to log sink:", err)
+		}
+	}
+}
+
+func (log *BasicLogger) closeSinks() (err error) {
+	log.sinkLock.Lock()
+	defer log.sinkLock.Unlock()
+	for _, sink := range log.sinks {
+		if closeErr := sink.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	log.sinks = nil
+	log.hasSinks.Store(false)
+	log.configSinks = nil
+	return
+}
+
+func (log *BasicLogger) reportError(prefix string, err error) {
+	log.StderrLock.Lock()
+	_, _ = os.Stderr.WriteString(prefix)
+	_, _ = os.Stderr.WriteString(" ")
+	_, _ = os.Stderr.WriteString(err.Error())
+	_, _ = os.Stderr.WriteString("\n")
+	log.StderrLock.Unlock()
+}
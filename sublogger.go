@@ -9,6 +9,7 @@ package maulogger
 
 import (
 	"fmt"
+	"time"
 )
 
 type Sublogger struct {
@@ -17,6 +18,8 @@ type Sublogger struct {
 	Module       string
 	DefaultLevel Level
 	metadata     map[string]interface{}
+	// buffer is set for Subloggers created with FingersCrossed and their children.
+	buffer *entryBuffer
 }
 
 // Subm creates a Sublogger
@@ -43,6 +46,25 @@ func (log *Sublogger) GetParent() Logger {
 	return log.parent
 }
 
+// Enabled checks if entries with the given level from this Sublogger's module would be written anywhere
+func (log *Sublogger) Enabled(level Level) bool {
+	return log.enabled(level)
+}
+
+func (log *Sublogger) enabled(level Level) bool {
+	if log.buffer != nil && log.buffer.captures(level) {
+		return true
+	}
+	return log.topLevel.enabled(level, log.Module)
+}
+
+// write sends the message to the buffer if this is a FingersCrossed Sublogger, and to the top-level logger otherwise.
+func (log *Sublogger) write(ts time.Time, level Level, message string) {
+	if log.buffer == nil || !log.buffer.add(log, ts, level, message) {
+		log.topLevel.raw(ts, level, log.metadata, log.Module, message, false)
+	}
+}
+
 // Sub creates a Sublogger
 func (log *Sublogger) Subm(module string, metadata map[string]interface{}) Logger {
 	if len(module) > 0 {
@@ -57,6 +79,7 @@ func (log *Sublogger) Subm(module string, metadata map[string]interface{}) Logge
 		Module:       module,
 		DefaultLevel: log.DefaultLevel,
 		metadata:     metadata,
+		buffer:       log.buffer,
 	}
 }
 
@@ -71,9 +94,15 @@ func (log *Sublogger) WithDefaultLevel(lvl Level) Logger {
 		parent:       log.parent,
 		Module:       log.Module,
 		DefaultLevel: lvl,
+		buffer:       log.buffer,
 	}
 }
 
+// Metadata returns the metadata attached to this Sublogger with Subm. The map must not be modified.
+func (log *Sublogger) Metadata() map[string]interface{} {
+	return log.metadata
+}
+
 // SetModule changes the module name of this Sublogger
 func (log *Sublogger) SetModule(mod string) {
 	log.Module = mod
@@ -91,126 +120,176 @@ func (log *Sublogger) SetParent(parent *BasicLogger) {
 
 //Write ...
 func (log *Sublogger) Write(p []byte) (n int, err error) {
-	log.topLevel.Raw(log.DefaultLevel, log.metadata, log.Module, string(p))
+	if log.enabled(log.DefaultLevel) {
+		log.write(time.Time{}, log.DefaultLevel, string(p))
+	}
 	return len(p), nil
 }
 
 // Log formats the given parts with fmt.Sprint and logs the result with the given level
 func (log *Sublogger) Log(level Level, parts ...interface{}) {
-	log.topLevel.Raw(level, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(level) {
+		log.write(time.Time{}, level, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Logln formats the given parts with fmt.Sprintln and logs the result with the given level
 func (log *Sublogger) Logln(level Level, parts ...interface{}) {
-	log.topLevel.Raw(level, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(level) {
+		log.write(time.Time{}, level, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Logf formats the given message and args with fmt.Sprintf and logs the result with the given level
 func (log *Sublogger) Logf(level Level, message string, args ...interface{}) {
-	log.topLevel.Raw(level, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(level) {
+		log.write(time.Time{}, level, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Logfln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the given level
 func (log *Sublogger) Logfln(level Level, message string, args ...interface{}) {
-	log.topLevel.Raw(level, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(level) {
+		log.write(time.Time{}, level, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }
 
 // Debug formats the given parts with fmt.Sprint and logs the result with the Debug level
 func (log *Sublogger) Debug(parts ...interface{}) {
-	log.topLevel.Raw(LevelDebug, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(LevelDebug) {
+		log.write(time.Time{}, LevelDebug, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Debugln formats the given parts with fmt.Sprintln and logs the result with the Debug level
 func (log *Sublogger) Debugln(parts ...interface{}) {
-	log.topLevel.Raw(LevelDebug, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(LevelDebug) {
+		log.write(time.Time{}, LevelDebug, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Debugf formats the given message and args with fmt.Sprintf and logs the result with the Debug level
 func (log *Sublogger) Debugf(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelDebug, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(LevelDebug) {
+		log.write(time.Time{}, LevelDebug, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Debugfln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the Debug level
 func (log *Sublogger) Debugfln(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelDebug, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(LevelDebug) {
+		log.write(time.Time{}, LevelDebug, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }
 
 // Info formats the given parts with fmt.Sprint and logs the result with the Info level
 func (log *Sublogger) Info(parts ...interface{}) {
-	log.topLevel.Raw(LevelInfo, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(LevelInfo) {
+		log.write(time.Time{}, LevelInfo, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Infoln formats the given parts with fmt.Sprintln and logs the result with the Info level
 func (log *Sublogger) Infoln(parts ...interface{}) {
-	log.topLevel.Raw(LevelInfo, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(LevelInfo) {
+		log.write(time.Time{}, LevelInfo, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Infof formats the given message and args with fmt.Sprintf and logs the result with the Info level
 func (log *Sublogger) Infof(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelInfo, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(LevelInfo) {
+		log.write(time.Time{}, LevelInfo, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Infofln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the Info level
 func (log *Sublogger) Infofln(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelInfo, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(LevelInfo) {
+		log.write(time.Time{}, LevelInfo, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }
 
 // Warn formats the given parts with fmt.Sprint and logs the result with the Warn level
 func (log *Sublogger) Warn(parts ...interface{}) {
-	log.topLevel.Raw(LevelWarn, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(LevelWarn) {
+		log.write(time.Time{}, LevelWarn, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Warnln formats the given parts with fmt.Sprintln and logs the result with the Warn level
 func (log *Sublogger) Warnln(parts ...interface{}) {
-	log.topLevel.Raw(LevelWarn, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(LevelWarn) {
+		log.write(time.Time{}, LevelWarn, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Warnf formats the given message and args with fmt.Sprintf and logs the result with the Warn level
 func (log *Sublogger) Warnf(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelWarn, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(LevelWarn) {
+		log.write(time.Time{}, LevelWarn, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Warnfln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the Warn level
 func (log *Sublogger) Warnfln(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelWarn, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(LevelWarn) {
+		log.write(time.Time{}, LevelWarn, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }
 
 // Error formats the given parts with fmt.Sprint and logs the result with the Error level
 func (log *Sublogger) Error(parts ...interface{}) {
-	log.topLevel.Raw(LevelError, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(LevelError) {
+		log.write(time.Time{}, LevelError, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Errorln formats the given parts with fmt.Sprintln and logs the result with the Error level
 func (log *Sublogger) Errorln(parts ...interface{}) {
-	log.topLevel.Raw(LevelError, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(LevelError) {
+		log.write(time.Time{}, LevelError, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Errorf formats the given message and args with fmt.Sprintf and logs the result with the Error level
 func (log *Sublogger) Errorf(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelError, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(LevelError) {
+		log.write(time.Time{}, LevelError, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Errorfln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the Error level
 func (log *Sublogger) Errorfln(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelError, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(LevelError) {
+		log.write(time.Time{}, LevelError, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }
 
 // Fatal formats the given parts with fmt.Sprint and logs the result with the Fatal level
 func (log *Sublogger) Fatal(parts ...interface{}) {
-	log.topLevel.Raw(LevelFatal, log.metadata, log.Module, fmt.Sprint(parts...))
+	if log.enabled(LevelFatal) {
+		log.write(time.Time{}, LevelFatal, fmt.Sprint(ResolveLazy(parts)...))
+	}
 }
 
 // Fatalln formats the given parts with fmt.Sprintln and logs the result with the Fatal level
 func (log *Sublogger) Fatalln(parts ...interface{}) {
-	log.topLevel.Raw(LevelFatal, log.metadata, log.Module, fmt.Sprintln(parts...))
+	if log.enabled(LevelFatal) {
+		log.write(time.Time{}, LevelFatal, fmt.Sprintln(ResolveLazy(parts)...))
+	}
 }
 
 // Fatalf formats the given message and args with fmt.Sprintf and logs the result with the Fatal level
 func (log *Sublogger) Fatalf(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelFatal, log.metadata, log.Module, fmt.Sprintf(message, args...))
+	if log.enabled(LevelFatal) {
+		log.write(time.Time{}, LevelFatal, fmt.Sprintf(message, ResolveLazy(args)...))
+	}
 }
 
 // Fatalfln formats the given message and args with fmt.Sprintf, appends a newline and logs the result with the Fatal level
 func (log *Sublogger) Fatalfln(message string, args ...interface{}) {
-	log.topLevel.Raw(LevelFatal, log.metadata, log.Module, fmt.Sprintf(message+"\n", args...))
+	if log.enabled(LevelFatal) {
+		log.write(time.Time{}, LevelFatal, fmt.Sprintf(message+"\n", ResolveLazy(args)...))
+	}
 }